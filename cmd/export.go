@@ -0,0 +1,270 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/rlp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	"github.com/cerc-io/leveldb-ethdb-rpc/pkg/client"
+)
+
+var (
+	exportURL       string
+	exportPrefix    string
+	exportToken     string
+	exportChunkSize int
+	exportLevelDB   string
+	exportFile      string
+	exportJWTSecret string
+	exportTLSCA     string
+	exportTLSCert   string
+	exportTLSKey    string
+	exportDatabase  string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a key prefix from a remote leveldb-ethdb-rpc server",
+	Long: `This command streams all the key/value pairs under a prefix from a leveldb-ethdb-rpc server
+and writes them to a local LevelDB database or to a flat file of RLP encoded [key, value] pairs.
+
+An interrupted export can be resumed by passing the last logged continuation token with --token.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		if err := export(); err != nil {
+			logWithCommand.Fatal(err)
+		}
+	},
+}
+
+// exportEntry is a key/value pair written to a flat export file
+type exportEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// exportSink is where exported key/value pairs are written, one chunk at a time
+type exportSink interface {
+	writeChunk(chunk *leveldb_ethdb_rpc.ExportChunk) error
+	Close() error
+}
+
+func export() error {
+	if (exportLevelDB == "") == (exportFile == "") {
+		return errors.New("exactly one of --out-leveldb or --out-file must be set")
+	}
+	prefix, err := hexutil.Decode(exportPrefix)
+	if err != nil {
+		return fmt.Errorf("invalid prefix: %v", err)
+	}
+	var token []byte
+	if exportToken != "" {
+		if token, err = hexutil.Decode(exportToken); err != nil {
+			return fmt.Errorf("invalid token: %v", err)
+		}
+	}
+
+	remote, err := dialClient(exportURL, exportDatabase, exportJWTSecret, exportTLSCA, exportTLSCert, exportTLSKey)
+	if err != nil {
+		return err
+	}
+
+	var sink exportSink
+	if exportLevelDB != "" {
+		sink, err = newLevelDBSink(exportLevelDB)
+	} else {
+		sink, err = newFileSink(exportFile, token != nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	// an interrupted export stops after the chunk in flight is cancelled, and can be resumed from its token
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logWithCommand.Infof("exporting prefix %s from %s", hexutil.Encode(prefix), exportURL)
+	var total int
+	for {
+		chunk, err := remote.ExportContext(ctx, prefix, token, exportChunkSize)
+		if err != nil {
+			return fmt.Errorf("export failed, resume with --token %s: %v", hexutil.Encode(token), err)
+		}
+		if err := sink.writeChunk(chunk); err != nil {
+			return fmt.Errorf("writing export failed, resume with --token %s: %v", hexutil.Encode(token), err)
+		}
+		total += len(chunk.Keys)
+		if chunk.Done {
+			break
+		}
+		token = chunk.Next
+		logWithCommand.Infof("exported %d entries, continuation token %s", total, hexutil.Encode(token))
+	}
+	logWithCommand.Infof("export complete, %d entries written", total)
+	return nil
+}
+
+// dialClient connects to the server at url with the authentication, TLS and database selection flags shared by
+// the client commands
+func dialClient(url, database, jwtSecret, tlsCA, tlsCert, tlsKey string) (*client.DatabaseClient, error) {
+	var opts []client.Option
+	if jwtSecret != "" {
+		secret, err := client.ReadJWTSecret(jwtSecret)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithJWTSecret(secret))
+	}
+	if tlsCA != "" || tlsCert != "" || tlsKey != "" {
+		tlsConfig, err := client.NewTLSConfig(tlsCA, tlsCert, tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithTLSConfig(tlsConfig))
+	}
+	if database != "" {
+		opts = append(opts, client.WithDatabase(database))
+	}
+	db, err := client.NewDatabaseClient(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return db.(*client.DatabaseClient), nil
+}
+
+// levelDBSink writes exported key/value pairs to a local LevelDB database
+type levelDBSink struct {
+	db *leveldb.Database
+}
+
+func newLevelDBSink(path string) (*levelDBSink, error) {
+	db, err := leveldb.New(path, 16, 16, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return &levelDBSink{db: db}, nil
+}
+
+func (s *levelDBSink) writeChunk(chunk *leveldb_ethdb_rpc.ExportChunk) error {
+	batch := s.db.NewBatchWithSize(ethdb.IdealBatchSize)
+	for i, key := range chunk.Keys {
+		if err := batch.Put(key, chunk.Values[i]); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+func (s *levelDBSink) Close() error {
+	return s.db.Close()
+}
+
+// fileSink writes exported key/value pairs to a flat file as a stream of RLP encoded [key, value] lists
+// each chunk is encoded in full before it is written, and a failed write is truncated away, so that the file
+// always ends with the last chunk whose continuation token was logged
+type fileSink struct {
+	file truncatingFile
+	// size is the length of the file up to the end of the last chunk written
+	size int64
+	buf  bytes.Buffer
+}
+
+// truncatingFile is the file written by a fileSink, an *os.File outside of tests
+type truncatingFile interface {
+	io.WriteCloser
+	io.Seeker
+	Truncate(size int64) error
+}
+
+func newFileSink(path string, resume bool) (*fileSink, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileSink{file: file, size: info.Size()}, nil
+}
+
+func (s *fileSink) writeChunk(chunk *leveldb_ethdb_rpc.ExportChunk) error {
+	s.buf.Reset()
+	for i, key := range chunk.Keys {
+		if err := rlp.Encode(&s.buf, &exportEntry{Key: key, Value: chunk.Values[i]}); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(s.buf.Bytes())
+	if err != nil {
+		if n > 0 {
+			if terr := s.file.Truncate(s.size); terr != nil {
+				return fmt.Errorf("%v; truncating the partially written chunk failed: %v", err, terr)
+			}
+			// a file not opened for appending would otherwise carry on writing past the end it was truncated to
+			if _, serr := s.file.Seek(s.size, io.SeekStart); serr != nil {
+				return fmt.Errorf("%v; seeking to the end of the last chunk failed: %v", err, serr)
+			}
+		}
+		return err
+	}
+	s.size += int64(n)
+	return nil
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVar(&exportURL, "url", "http://127.0.0.1:8500", "leveldb-ethdb-rpc server endpoint")
+	exportCmd.Flags().StringVar(&exportDatabase, "database", "", "name of the database to export from a server serving several; the default database if unset")
+	exportCmd.Flags().StringVar(&exportPrefix, "prefix", "0x", "hex encoded key prefix to export; all keys by default")
+	exportCmd.Flags().StringVar(&exportToken, "token", "", "hex encoded continuation token to resume an interrupted export from")
+	exportCmd.Flags().IntVar(&exportChunkSize, "chunk-size", 1000, "number of key/value pairs requested per chunk")
+	exportCmd.Flags().StringVar(&exportLevelDB, "out-leveldb", "", "filesystem path of the leveldb database to write the export to")
+	exportCmd.Flags().StringVar(&exportFile, "out-file", "", "filesystem path of the flat file to write the export to")
+	exportCmd.Flags().StringVar(&exportTLSCA, "tls-ca", "", "PEM CA bundle trusted to sign the server certificate; system roots by default")
+	exportCmd.Flags().StringVar(&exportTLSCert, "tls-cert", "", "PEM client certificate presented to servers requiring mutual TLS")
+	exportCmd.Flags().StringVar(&exportTLSKey, "tls-key", "", "PEM private key of the client certificate")
+	exportCmd.Flags().StringVar(&exportJWTSecret, "jwt-secret", "", "path to the hex encoded JWT secret shared with the server")
+}
@@ -0,0 +1,222 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	srpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg/rpc"
+)
+
+const testExportKeys = 1000
+
+func testExportKey(i int) []byte   { return []byte(fmt.Sprintf("k%05d", i)) }
+func testExportValue(i int) []byte { return []byte(fmt.Sprintf("v%d", i)) }
+
+// interruptExport sends the process an interrupt while the nth export call is in flight,
+// holding the call until the client gives up on it
+func interruptExport(n int32) func(http.Handler) http.Handler {
+	var calls atomic.Int32
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(leveldb_ethdb_rpc.APIName+"_export")) && calls.Add(1) == n {
+				syscall.Kill(os.Getpid(), syscall.SIGINT)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// startTestExportServer serves a database holding the test export keys over HTTP, through middleware,
+// and returns the URL of the endpoint
+func startTestExportServer(t *testing.T, middleware func(http.Handler) http.Handler) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: leveldb_ethdb_rpc.EngineLevelDB, Directory: path, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < testExportKeys; i++ {
+		if err := db.Put(testExportKey(i), testExportValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := leveldb_ethdb_rpc.NewServer(&leveldb_ethdb_rpc.Config{FilePath: path, Cache: 16, Handles: 16}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Serve(new(sync.WaitGroup))
+	t.Cleanup(func() { srv.Stop() })
+	httpSrv, _, err := srpc.StartHTTPEndpoint("127.0.0.1:0", srv.APIs(), []string{leveldb_ethdb_rpc.APIName}, nil, []string{"*"},
+		nil, nil, rpc.DefaultHTTPTimeouts, middleware, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { httpSrv.Shutdown(context.Background()) })
+	return httpSrv.Endpoint()
+}
+
+// readExportFile decodes the key/value pairs of a flat export file
+func readExportFile(t *testing.T, path string) []exportEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stream := rlp.NewStream(file, 0)
+	var entries []exportEntry
+	for {
+		var entry exportEntry
+		if err := stream.Decode(&entry); err == io.EOF {
+			return entries
+		} else if err != nil {
+			t.Fatalf("entry %d: %v", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// TestExportResume interrupts an export to a flat file part way, resumes it from the logged token,
+// and compares the file with the source database
+func TestExportResume(t *testing.T) {
+	logWithCommand = *log.WithField("SubCommand", "export")
+
+	exportURL = startTestExportServer(t, interruptExport(4))
+	exportFile = filepath.Join(t.TempDir(), "export.rlp")
+	exportLevelDB, exportPrefix, exportToken, exportChunkSize = "", "0x", "", 100
+
+	err := export()
+	if err == nil {
+		t.Fatal("expected the interrupted export to fail")
+	}
+	match := regexp.MustCompile(`resume with --token (0x[0-9a-f]+)`).FindStringSubmatch(err.Error())
+	if match == nil {
+		t.Fatalf("no continuation token in %q", err)
+	}
+	// the file ends with the last chunk exported before the interrupt
+	if entries := readExportFile(t, exportFile); len(entries) != 300 {
+		t.Fatalf("have %d entries after the interrupt, want 300", len(entries))
+	}
+
+	exportToken = match[1]
+	if err := export(); err != nil {
+		t.Fatal(err)
+	}
+	entries := readExportFile(t, exportFile)
+	if len(entries) != testExportKeys {
+		t.Fatalf("have %d entries, want %d", len(entries), testExportKeys)
+	}
+	for i, entry := range entries {
+		if !bytes.Equal(entry.Key, testExportKey(i)) || !bytes.Equal(entry.Value, testExportValue(i)) {
+			t.Fatalf("entry %d: have %q=%q, want %q=%q", i, entry.Key, entry.Value, testExportKey(i), testExportValue(i))
+		}
+	}
+}
+
+// shortFile is a file that fails once left bytes have been written to it, having written them
+type shortFile struct {
+	*os.File
+	left int
+}
+
+func (f *shortFile) Write(b []byte) (int, error) {
+	if len(b) <= f.left {
+		f.left -= len(b)
+		return f.File.Write(b)
+	}
+	n, err := f.File.Write(b[:f.left])
+	f.left = 0
+	if err != nil {
+		return n, err
+	}
+	return n, errors.New("no space left on device")
+}
+
+func TestFileSinkTruncatesFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.rlp")
+	sink, err := newFileSink(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.file = &shortFile{File: sink.file.(*os.File), left: 100}
+	defer sink.Close()
+
+	chunk := func(from, to int) *leveldb_ethdb_rpc.ExportChunk {
+		c := new(leveldb_ethdb_rpc.ExportChunk)
+		for i := from; i < to; i++ {
+			c.Keys, c.Values = append(c.Keys, testExportKey(i)), append(c.Values, testExportValue(i))
+		}
+		return c
+	}
+	if err := sink.writeChunk(chunk(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	// the second chunk only partly fits, and is truncated away
+	if err := sink.writeChunk(chunk(3, 10)); err == nil || !strings.Contains(err.Error(), "no space left") {
+		t.Fatalf("have error %v, want no space left", err)
+	}
+	if entries := readExportFile(t, path); len(entries) != 3 {
+		t.Fatalf("have %d entries after the failed write, want 3", len(entries))
+	}
+
+	// the next write carries on from the end of the last complete chunk
+	sink.file.(*shortFile).left = 1 << 20
+	if err := sink.writeChunk(chunk(3, 10)); err != nil {
+		t.Fatal(err)
+	}
+	entries := readExportFile(t, path)
+	if len(entries) != 10 {
+		t.Fatalf("have %d entries, want 10", len(entries))
+	}
+	for i, entry := range entries {
+		if !bytes.Equal(entry.Key, testExportKey(i)) {
+			t.Fatalf("entry %d: have key %q, want %q", i, entry.Key, testExportKey(i))
+		}
+	}
+}
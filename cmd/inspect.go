@@ -0,0 +1,156 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	"github.com/cerc-io/leveldb-ethdb-rpc/pkg/client"
+)
+
+var (
+	inspectURL       string
+	inspectPrefix    string
+	inspectRefresh   bool
+	inspectJWTSecret string
+	inspectTLSCA     string
+	inspectTLSCert   string
+	inspectTLSKey    string
+	inspectDatabase  string
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Inspect the contents of a remote leveldb-ethdb-rpc server's database",
+	Long: `This command reports the size and number of the entries of each category of data in the database
+of a leveldb-ethdb-rpc server, like geth's db inspect, optionally restricted to the keys under a prefix.
+
+The server caches the last result for a prefix, which is returned unless --refresh is set.
+The progress of the scan is logged when the server is reached over a websocket or IPC endpoint.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		if err := inspect(); err != nil {
+			logWithCommand.Fatal(err)
+		}
+	},
+}
+
+func inspect() error {
+	prefix, err := hexutil.Decode(inspectPrefix)
+	if err != nil {
+		return fmt.Errorf("invalid prefix: %v", err)
+	}
+	remote, err := dialClient(inspectURL, inspectDatabase, inspectJWTSecret, inspectTLSCA, inspectTLSCert, inspectTLSKey)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	// the scan carries on on the server if interrupted, and its result is cached for the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logWithCommand.Infof("inspecting prefix %s of %s", hexutil.Encode(prefix), inspectURL)
+	var result *leveldb_ethdb_rpc.InspectResult
+	if streamsNotifications(inspectURL) {
+		result, err = inspectWithProgress(ctx, remote, prefix)
+	} else {
+		result, err = remote.InspectContext(ctx, prefix, inspectRefresh)
+	}
+	if err != nil {
+		return err
+	}
+	return writeInspectResult(os.Stdout, result)
+}
+
+// streamsNotifications reports whether the endpoint at rawurl is reached over a transport supporting
+// subscriptions, a websocket or IPC endpoint rather than an HTTP one
+func streamsNotifications(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	return err != nil || (u.Scheme != "http" && u.Scheme != "https")
+}
+
+// inspectWithProgress runs the inspection through a subscription, logging its progress until it is done
+func inspectWithProgress(ctx context.Context, remote *client.DatabaseClient, prefix []byte) (*leveldb_ethdb_rpc.InspectResult, error) {
+	ch := make(chan *leveldb_ethdb_rpc.InspectProgress)
+	sub, err := remote.SubscribeInspect(ctx, prefix, inspectRefresh, ch)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case progress := <-ch:
+			switch {
+			case progress.Error != "":
+				return nil, errors.New(progress.Error)
+			case progress.Result != nil:
+				return progress.Result, nil
+			}
+			logWithCommand.Infof("inspected %d keys, %s, in %s", progress.Keys, common.StorageSize(progress.Size), progress.Elapsed.Round(time.Millisecond))
+		case err := <-sub.Err():
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// writeInspectResult writes the result as a table, like geth's db inspect
+func writeInspectResult(out io.Writer, result *leveldb_ethdb_rpc.InspectResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATABASE\tCATEGORY\tSIZE\tITEMS")
+	for _, stat := range result.Stats {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", stat.Database, stat.Category, common.StorageSize(stat.Size), stat.Count)
+	}
+	fmt.Fprintf(w, "\tTotal\t%s\t%d keys\n", common.StorageSize(result.Total), result.Keys)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	logWithCommand.Infof("scanned at %s in %s", result.Finished.Format("2006-01-02 15:04:05"), result.Elapsed.Round(time.Millisecond))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().StringVar(&inspectURL, "url", "http://127.0.0.1:8500", "leveldb-ethdb-rpc server endpoint")
+	inspectCmd.Flags().StringVar(&inspectDatabase, "database", "", "name of the database to inspect on a server serving several; the default database if unset")
+	inspectCmd.Flags().StringVar(&inspectPrefix, "prefix", "0x", "hex encoded key prefix to inspect; the whole database, including the freezer, by default")
+	inspectCmd.Flags().BoolVar(&inspectRefresh, "refresh", false, "scan the database again instead of returning the server's last result")
+	inspectCmd.Flags().StringVar(&inspectTLSCA, "tls-ca", "", "PEM CA bundle trusted to sign the server certificate; system roots by default")
+	inspectCmd.Flags().StringVar(&inspectTLSCert, "tls-cert", "", "PEM client certificate presented to servers requiring mutual TLS")
+	inspectCmd.Flags().StringVar(&inspectTLSKey, "tls-key", "", "PEM private key of the client certificate")
+	inspectCmd.Flags().StringVar(&inspectJWTSecret, "jwt-secret", "", "path to the hex encoded JWT secret shared with the server")
+}
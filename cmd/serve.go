@@ -17,10 +17,19 @@
 package cmd
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -58,29 +67,153 @@ func serve() {
 		logWithCommand.Fatal(err)
 	}
 	logWithCommand.Infof("server config: %+v", serverConfig)
-	logWithCommand.Debug("initializing new server service")
-	server, err := leveldb_ethdb_rpc.NewServer(serverConfig)
+	if serverConfig.MetricsEnabled {
+		// must be set before the database and the endpoints are opened, as they register their metrics on creation
+		metrics.Enabled = true
+	}
+	logWithCommand.Debug("initializing new server services")
+	// the limiter is shared by the databases, so that the cap on concurrent reads holds over all of them
+	limiter := leveldb_ethdb_rpc.NewRateLimiter(serverConfig)
+	if limiter != nil {
+		logWithCommand.Info("rate limiting enabled")
+	}
+	servers, err := newServers(serverConfig, limiter)
 	if err != nil {
 		logWithCommand.Fatal(err)
 	}
 
 	logWithCommand.Info("starting up servers")
-	server.Serve(wg)
-	if err := startServers(server, serverConfig); err != nil {
+	for _, db := range servers {
+		db.server.Serve(wg)
+	}
+	eps := new(endpoints)
+	if err := startServers(servers, serverConfig, limiter, eps); err != nil {
+		eps.shutdown(context.Background())
+		servers.stop()
 		logWithCommand.Fatal(err)
 	}
 
 	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt)
-	<-shutdown
-	server.Stop()
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	sig := <-shutdown
+	logWithCommand.Infof("received %s, shutting down", sig)
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+	eps.shutdown(ctx)
+	servers.stop()
 	wg.Wait()
+	logWithCommand.Info("shutdown complete")
+}
+
+// databaseServer is the server of one of the configured databases; the default database has no name
+type databaseServer struct {
+	name   string
+	server leveldb_ethdb_rpc.Server
+}
+
+// databaseServers are the servers of the default database, if it has a path, and of the named databases
+type databaseServers []databaseServer
+
+// newServers opens every configured database, closing those already opened if one fails
+// The calls to the databases are admitted by limiter, if it is not nil
+func newServers(settings *leveldb_ethdb_rpc.Config, limiter *leveldb_ethdb_rpc.RateLimiter) (databaseServers, error) {
+	var servers databaseServers
+	if settings.FilePath != "" {
+		server, err := leveldb_ethdb_rpc.NewServer(settings, limiter)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, databaseServer{server: server})
+	}
+	for _, db := range settings.Databases {
+		logWithCommand.Infof("opening database %s", db.Name)
+		server, err := leveldb_ethdb_rpc.NewServer(settings.ForDatabase(db), limiter)
+		if err != nil {
+			servers.stop()
+			return nil, fmt.Errorf("database %s: %w", db.Name, err)
+		}
+		servers = append(servers, databaseServer{name: db.Name, server: server})
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("no database configured")
+	}
+	return servers, nil
+}
+
+// defaultServer returns the server of the default database, or nil if there is none
+func (s databaseServers) defaultServer() leveldb_ethdb_rpc.Server {
+	if len(s) > 0 && s[0].name == "" {
+		return s[0].server
+	}
+	return nil
+}
+
+// stop closes every database
+func (s databaseServers) stop() {
+	for _, db := range s {
+		db.server.Stop()
+	}
+}
+
+// endpoints holds the servers started by serve, so that they can be shut down
+type endpoints struct {
+	ipc  *srpc.IPCServer
+	http []*srpc.HTTPServer
+}
+
+// shutdown stops the endpoints in reverse order of their start, draining in-flight HTTP requests until ctx is done
+func (e *endpoints) shutdown(ctx context.Context) {
+	for i := len(e.http) - 1; i >= 0; i-- {
+		if err := e.http[i].Shutdown(ctx); err != nil {
+			logWithCommand.WithError(err).Errorf("failed to shut down endpoint %s", e.http[i].Endpoint())
+		}
+	}
+	if e.ipc != nil {
+		if err := e.ipc.Close(); err != nil {
+			logWithCommand.WithError(err).Error("failed to close IPC server")
+		}
+	}
+}
+
+// httpRoutes returns the routes served next to the RPC handler of a database
+// the binary transport is rate limited like the RPC handler, if limiter is not nil
+func httpRoutes(server leveldb_ethdb_rpc.Server, limiter *leveldb_ethdb_rpc.RateLimiter) []srpc.Route {
+	binary := server.BinaryHandler()
+	if limiter != nil {
+		binary = limiter.Handler(binary)
+	}
+	return []srpc.Route{
+		{Path: leveldb_ethdb_rpc.BinaryPath, Handler: binary},
+		{Path: leveldb_ethdb_rpc.HealthPath, Handler: server.HealthHandler(), Unauthenticated: true},
+		{Path: leveldb_ethdb_rpc.ReadyPath, Handler: server.ReadyHandler(), Unauthenticated: true},
+	}
 }
 
-func startServers(server leveldb_ethdb_rpc.Server, settings *leveldb_ethdb_rpc.Config) error {
+// startServers starts the configured endpoints, the HTTP requests throttled by limiter if it is not nil
+func startServers(servers databaseServers, settings *leveldb_ethdb_rpc.Config, limiter *leveldb_ethdb_rpc.RateLimiter, eps *endpoints) error {
+	var (
+		apis      []rpc.API
+		routes    []srpc.Route
+		databases []srpc.Database
+	)
+	server := servers.defaultServer()
+	if server != nil {
+		apis = server.APIs()
+		routes = httpRoutes(server, limiter)
+	}
+	for _, db := range servers {
+		if db.name != "" {
+			databases = append(databases, srpc.Database{Name: db.name, APIs: db.server.APIs(), Routes: httpRoutes(db.server, limiter)})
+		}
+	}
+
 	if settings.IPCEnabled {
+		if server == nil {
+			return errors.New("the IPC server only serves the default database, which has no path")
+		}
 		logWithCommand.Info("starting up IPC server")
-		_, _, err := srpc.StartIPCEndpoint(settings.IPCEndpoint, server.APIs())
+		var err error
+		eps.ipc, _, err = srpc.StartIPCEndpoint(settings.IPCEndpoint, apis)
 		if err != nil {
 			return err
 		}
@@ -88,16 +221,63 @@ func startServers(server leveldb_ethdb_rpc.Server, settings *leveldb_ethdb_rpc.C
 		logWithCommand.Info("IPC server is disabled")
 	}
 
+	if settings.MetricsEnabled {
+		logWithCommand.Info("starting up metrics server")
+		metricsServer, err := srpc.StartMetricsEndpoint(settings.MetricsEndpoint)
+		if err != nil {
+			return err
+		}
+		eps.http = append(eps.http, metricsServer)
+	} else {
+		logWithCommand.Info("metrics server is disabled")
+	}
+
+	var jwtSecret []byte
+	if settings.JWTSecretPath != "" && (settings.HTTPEnabled || settings.WSEnabled) {
+		var err error
+		jwtSecret, err = node.ObtainJWTSecret(settings.JWTSecretPath)
+		if err != nil {
+			return err
+		}
+		logWithCommand.Infof("JWT authentication enabled with secret %s", settings.JWTSecretPath)
+	}
+
 	if settings.HTTPEnabled {
 		logWithCommand.Info("starting up HTTP server")
-		_, err := srpc.StartHTTPEndpoint(settings.HTTPEndpoint, server.APIs(), []string{"leveldb"}, nil, []string{"*"}, rpc.HTTPTimeouts{})
+		var tlsConfig *tls.Config
+		if settings.TLSCertFile != "" || settings.TLSKeyFile != "" {
+			var err error
+			tlsConfig, err = srpc.NewTLSConfig(settings.TLSCertFile, settings.TLSKeyFile, settings.TLSClientCAFile)
+			if err != nil {
+				return err
+			}
+		} else if settings.TLSClientCAFile != "" {
+			return errors.New("a TLS client CA requires a TLS certificate and key")
+		}
+		var middleware func(http.Handler) http.Handler
+		if limiter != nil {
+			middleware = limiter.Handler
+		}
+		httpServer, _, err := srpc.StartHTTPEndpoint(settings.HTTPEndpoint, apis, settings.HTTPModules, settings.HTTPCors, settings.HTTPVhosts, jwtSecret, tlsConfig, settings.HTTPTimeouts, middleware, databases, routes...)
 		if err != nil {
 			return err
 		}
+		eps.http = append(eps.http, httpServer)
 	} else {
 		logWithCommand.Info("HTTP server is disabled")
 	}
 
+	if settings.WSEnabled {
+		logWithCommand.Info("starting up WS server")
+		wsServer, _, err := srpc.StartWSEndpoint(settings.WSEndpoint, apis, settings.WSModules, settings.WSOrigins, jwtSecret, databases)
+		if err != nil {
+			return err
+		}
+		eps.http = append(eps.http, wsServer)
+	} else {
+		logWithCommand.Info("WS server is disabled")
+	}
+
 	return nil
 }
 
@@ -109,20 +289,78 @@ func init() {
 	serveCmd.PersistentFlags().String("ipc-path", "", "ipc server endpoint")
 	serveCmd.PersistentFlags().Bool("http-enabled", true, "turn on http server; on by default")
 	serveCmd.PersistentFlags().String("http-path", "127.0.0.1:8500", "http server endpoint; default = 127.0.0.1:8545")
+	serveCmd.PersistentFlags().StringSlice("http-cors", nil, "comma separated list of origins allowed to make cross-origin http requests")
+	serveCmd.PersistentFlags().StringSlice("http-vhosts", []string{"*"}, "comma separated list of virtual hostnames accepted by the http server")
+	serveCmd.PersistentFlags().StringSlice("http-modules", []string{leveldb_ethdb_rpc.APIName, leveldb_ethdb_rpc.ChainDataAPIName}, "comma separated list of API namespaces served over http")
+	serveCmd.PersistentFlags().Duration("http-read-timeout", rpc.DefaultHTTPTimeouts.ReadTimeout, "maximum duration for reading an http request")
+	serveCmd.PersistentFlags().Duration("http-write-timeout", rpc.DefaultHTTPTimeouts.WriteTimeout, "maximum duration for writing an http response")
+	serveCmd.PersistentFlags().Duration("http-idle-timeout", rpc.DefaultHTTPTimeouts.IdleTimeout, "maximum duration an idle http keep-alive connection is kept open")
+	serveCmd.PersistentFlags().Bool("ws-enabled", false, "turn on websocket server")
+	serveCmd.PersistentFlags().String("ws-path", "127.0.0.1:8501", "websocket server endpoint; default = 127.0.0.1:8501")
+	serveCmd.PersistentFlags().StringSlice("ws-origins", nil, "comma separated list of origins browsers may open websockets from; localhost only if empty")
+	serveCmd.PersistentFlags().StringSlice("ws-modules", []string{leveldb_ethdb_rpc.APIName, leveldb_ethdb_rpc.ChainDataAPIName}, "comma separated list of API namespaces served over websockets")
+	serveCmd.PersistentFlags().Bool("metrics-enabled", false, "turn on metrics collection and the prometheus metrics server")
+	serveCmd.PersistentFlags().String("metrics-path", "127.0.0.1:6060", "prometheus metrics server endpoint, served on /metrics")
+	serveCmd.PersistentFlags().Duration("health-staleness", 0, "report the server not ready if the head block hasn't advanced for this long; off if 0")
+	serveCmd.PersistentFlags().Duration("shutdown-timeout", 30*time.Second, "how long in-flight http requests are given to complete on shutdown")
+	serveCmd.PersistentFlags().Float64("rate-limit", 0, "calls per second allowed for each client, identified by JWT subject or IP; off if 0")
+	serveCmd.PersistentFlags().Int("rate-burst", 0, "calls a client may burst above the rate limit; one second worth of calls if 0")
+	serveCmd.PersistentFlags().Int("max-concurrent-reads", 0, "maximum number of calls served at the same time over all endpoints; unlimited if 0")
+	serveCmd.PersistentFlags().Uint64("ancient-range-max-count", 2048, "maximum number of items returned by a single ancient range read; unlimited if 0")
+	serveCmd.PersistentFlags().Uint64("ancient-range-max-bytes", 32*1024*1024, "maximum number of bytes returned by a single ancient range read; unlimited if 0")
+	serveCmd.PersistentFlags().Int("max-snapshots", 256, "maximum number of snapshots held open for clients at the same time; unlimited if 0")
+	serveCmd.PersistentFlags().Int("max-iterators", 1024, "maximum number of iterators held open for clients at the same time; unlimited if 0")
+	serveCmd.PersistentFlags().String("jwt-secret", "", "path to the hex encoded JWT secret required by the http and websocket servers; generated if missing")
+	serveCmd.PersistentFlags().String("tls-cert", "", "PEM certificate file; the http server uses HTTPS if set")
+	serveCmd.PersistentFlags().String("tls-key", "", "PEM private key file of the tls certificate")
+	serveCmd.PersistentFlags().String("tls-client-ca", "", "PEM CA bundle; http clients must present a certificate signed by it if set")
 
 	serveCmd.PersistentFlags().String("leveldb-path", "", "leveldb filesystem path")
 	serveCmd.PersistentFlags().Int("leveldb-cache-size", 0, "leveldb cache size")
 	serveCmd.PersistentFlags().String("leveldb-ancient-path", "", "filesystem path to freezer")
 	serveCmd.PersistentFlags().String("leveldb-namespace", "eth/db/chaindata/", "leveldb namespace")
+	serveCmd.PersistentFlags().Bool("leveldb-write-enabled", false, "open leveldb read-write and turn on the write endpoints")
+	serveCmd.PersistentFlags().String("leveldb-engine", "", "storage engine: leveldb, pebble or memorydb; detected from the database directory if unset")
+	serveCmd.PersistentFlags().Bool("leveldb-follow", false, "reopen the read-only database periodically and on manifest changes to pick up the changes of a replica updated in place; not the datadir of a running geth, which holds its lock")
+	serveCmd.PersistentFlags().Duration("leveldb-follow-interval", 10*time.Second, "how often the database is reopened in follow mode; only on manifest changes if 0")
 
 	// toml bindings
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_IPC_ENABLED, serveCmd.PersistentFlags().Lookup("ipc-enabled"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_IPC_ENDPOINT, serveCmd.PersistentFlags().Lookup("ipc-path"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_ENABLED, serveCmd.PersistentFlags().Lookup("http-enabled"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_ENDPOINT, serveCmd.PersistentFlags().Lookup("http-path"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_CORS, serveCmd.PersistentFlags().Lookup("http-cors"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_VHOSTS, serveCmd.PersistentFlags().Lookup("http-vhosts"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_MODULES, serveCmd.PersistentFlags().Lookup("http-modules"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_READ_TIMEOUT, serveCmd.PersistentFlags().Lookup("http-read-timeout"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_WRITE_TIMEOUT, serveCmd.PersistentFlags().Lookup("http-write-timeout"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HTTP_IDLE_TIMEOUT, serveCmd.PersistentFlags().Lookup("http-idle-timeout"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_WS_ENABLED, serveCmd.PersistentFlags().Lookup("ws-enabled"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_WS_ENDPOINT, serveCmd.PersistentFlags().Lookup("ws-path"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_WS_ORIGINS, serveCmd.PersistentFlags().Lookup("ws-origins"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_WS_MODULES, serveCmd.PersistentFlags().Lookup("ws-modules"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_METRICS_ENABLED, serveCmd.PersistentFlags().Lookup("metrics-enabled"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_METRICS_ENDPOINT, serveCmd.PersistentFlags().Lookup("metrics-path"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_HEALTH_STALENESS, serveCmd.PersistentFlags().Lookup("health-staleness"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_SHUTDOWN_TIMEOUT, serveCmd.PersistentFlags().Lookup("shutdown-timeout"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_RATE_LIMIT, serveCmd.PersistentFlags().Lookup("rate-limit"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_RATE_BURST, serveCmd.PersistentFlags().Lookup("rate-burst"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_MAX_CONCURRENT_READS, serveCmd.PersistentFlags().Lookup("max-concurrent-reads"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_ANCIENT_RANGE_MAX_COUNT, serveCmd.PersistentFlags().Lookup("ancient-range-max-count"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_ANCIENT_RANGE_MAX_BYTES, serveCmd.PersistentFlags().Lookup("ancient-range-max-bytes"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_MAX_SNAPSHOTS, serveCmd.PersistentFlags().Lookup("max-snapshots"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_MAX_ITERATORS, serveCmd.PersistentFlags().Lookup("max-iterators"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_JWT_SECRET, serveCmd.PersistentFlags().Lookup("jwt-secret"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_TLS_CERT, serveCmd.PersistentFlags().Lookup("tls-cert"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_TLS_KEY, serveCmd.PersistentFlags().Lookup("tls-key"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_TLS_CLIENT_CA, serveCmd.PersistentFlags().Lookup("tls-client-ca"))
 
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_PATH, serveCmd.PersistentFlags().Lookup("leveldb-path"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_CACHE_SIZE, serveCmd.PersistentFlags().Lookup("leveldb-cache-size"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_ANCIENT_PATH, serveCmd.PersistentFlags().Lookup("leveldb-ancient-path"))
 	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_NAMESPACE, serveCmd.PersistentFlags().Lookup("leveldb-namespace"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_WRITE_ENABLED, serveCmd.PersistentFlags().Lookup("leveldb-write-enabled"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_ENGINE, serveCmd.PersistentFlags().Lookup("leveldb-engine"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_FOLLOW, serveCmd.PersistentFlags().Lookup("leveldb-follow"))
+	viper.BindPFlag(leveldb_ethdb_rpc.TOML_LEVELDB_FOLLOW_INTERVAL, serveCmd.PersistentFlags().Lookup("leveldb-follow-interval"))
 }
@@ -0,0 +1,188 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	"github.com/cerc-io/leveldb-ethdb-rpc/pkg/client"
+)
+
+// newTestDatabase writes a leveldb database holding the keys a, b and c, mapped to themselves, in dir
+// and returns its path
+func newTestDatabase(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: leveldb_ethdb_rpc.EngineLevelDB, Directory: path, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := db.Put([]byte(key), []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestShutdown runs the shutdown sequence of serve against a server with HTTP and IPC endpoints,
+// with an iterator and a snapshot still held by a client
+func TestShutdown(t *testing.T) {
+	logWithCommand = *log.WithField("SubCommand", "serve")
+
+	dir := t.TempDir()
+	path := newTestDatabase(t, dir)
+	settings := &leveldb_ethdb_rpc.Config{
+		IPCEnabled:   true,
+		IPCEndpoint:  filepath.Join(dir, "leveldb.ipc"),
+		HTTPEnabled:  true,
+		HTTPEndpoint: "127.0.0.1:0",
+		HTTPVhosts:   []string{"*"},
+		HTTPModules:  []string{leveldb_ethdb_rpc.APIName},
+		HTTPTimeouts: rpc.DefaultHTTPTimeouts,
+		FilePath:     path,
+		Cache:        16,
+		Handles:      16,
+	}
+	servers, err := newServers(settings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := new(sync.WaitGroup)
+	for _, db := range servers {
+		db.server.Serve(wg)
+	}
+	eps := new(endpoints)
+	if err := startServers(servers, settings, nil, eps); err != nil {
+		eps.shutdown(context.Background())
+		servers.stop()
+		t.Fatal(err)
+	}
+
+	var remotes []*client.DatabaseClient
+	for _, url := range []string{eps.http[0].Endpoint(), settings.IPCEndpoint} {
+		remote, err := client.NewDatabaseClient(url, client.WithRetry(0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer remote.Close()
+		if value, err := remote.Get([]byte("a")); err != nil || string(value) != "a" {
+			t.Fatalf("%s: have %q, %v, want a", url, value, err)
+		}
+		remotes = append(remotes, remote.(*client.DatabaseClient))
+	}
+	it := remotes[0].NewIterator(nil, nil)
+	if !it.Next() {
+		t.Fatal(it.Error())
+	}
+	if _, err := remotes[0].NewSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		eps.shutdown(ctx)
+		servers.stop()
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutdown didn't complete")
+	}
+
+	for i, remote := range remotes {
+		if _, err := remote.Get([]byte("a")); err == nil {
+			t.Fatalf("client %d: expected requests to fail after shutdown", i)
+		}
+	}
+	if _, err := os.Stat(settings.IPCEndpoint); !os.IsNotExist(err) {
+		t.Fatalf("IPC socket not removed: %v", err)
+	}
+	// the iterator and snapshot were released, so the database is closed and its lock can be taken again
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: leveldb_ethdb_rpc.EngineLevelDB, Directory: path, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatalf("database not closed: %v", err)
+	}
+	if value, err := db.Get([]byte("c")); err != nil || string(value) != "c" {
+		t.Fatalf("have %q, %v, want c", value, err)
+	}
+	db.Close()
+	it.Release()
+}
+
+// TestStartFailure checks that an endpoint failing to start is reported to serve, which then closes the endpoints
+// already started and the databases, rather than the process exiting
+func TestStartFailure(t *testing.T) {
+	logWithCommand = *log.WithField("SubCommand", "serve")
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	dir := t.TempDir()
+	path := newTestDatabase(t, dir)
+	settings := &leveldb_ethdb_rpc.Config{
+		IPCEnabled:   true,
+		IPCEndpoint:  filepath.Join(dir, "leveldb.ipc"),
+		HTTPEnabled:  true,
+		HTTPEndpoint: taken.Addr().String(),
+		HTTPModules:  []string{leveldb_ethdb_rpc.APIName},
+		HTTPTimeouts: rpc.DefaultHTTPTimeouts,
+		FilePath:     path,
+		Cache:        16,
+		Handles:      16,
+	}
+	servers, err := newServers(settings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := new(endpoints)
+	if err := startServers(servers, settings, nil, eps); err == nil {
+		t.Fatal("expected the HTTP endpoint to fail on a port in use")
+	}
+	eps.shutdown(context.Background())
+	servers.stop()
+
+	if _, err := os.Stat(settings.IPCEndpoint); !os.IsNotExist(err) {
+		t.Fatalf("IPC socket not removed: %v", err)
+	}
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: leveldb_ethdb_rpc.EngineLevelDB, Directory: path, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatalf("database not closed: %v", err)
+	}
+	db.Close()
+}
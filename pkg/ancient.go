@@ -0,0 +1,141 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// MaxAncientOps is the maximum number of operations that can be executed in a single ReadAncients call
+const MaxAncientOps = 1024
+
+// The freezer read operations that can be executed by ReadAncients
+const (
+	AncientOpHasAncient   = "hasAncient"
+	AncientOpAncient      = "ancient"
+	AncientOpAncientRange = "ancientRange"
+	AncientOpAncients     = "ancients"
+	AncientOpTail         = "tail"
+	AncientOpAncientSize  = "ancientSize"
+)
+
+// AncientOp is a single freezer read executed as part of ReadAncients
+// Number is the item number for hasAncient and ancient, and the first item for ancientRange
+type AncientOp struct {
+	Op       string `json:"op"`
+	Kind     string `json:"kind,omitempty"`
+	Number   uint64 `json:"number,omitempty"`
+	Count    uint64 `json:"count,omitempty"`
+	MaxBytes uint64 `json:"maxBytes,omitempty"`
+}
+
+// AncientOpResult is the result of a single AncientOp
+// only the field matching the operation is set; Error is set instead if the operation failed
+type AncientOpResult struct {
+	Has    bool     `json:"has,omitempty"`
+	Value  []byte   `json:"value,omitempty"`
+	Values [][]byte `json:"values,omitempty"`
+	Number uint64   `json:"number,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	// Truncated is set if the server's caps cut an ancientRange short of the requested count and maxBytes
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// AncientRangeResult is the result of AncientRangePage
+type AncientRangeResult struct {
+	Items [][]byte `json:"items"`
+	// Truncated is set if the server's caps cut the range short of the requested count and maxBytes,
+	// in which case the remaining items can be requested starting after the last returned one
+	Truncated bool `json:"truncated"`
+}
+
+// ancientRangeCaps are the server's limits on the number of items and bytes returned by a single range read
+// a cap is off if 0
+type ancientRangeCaps struct {
+	count uint64
+	bytes uint64
+}
+
+// read reads a range of freezer items within the caps, and reports whether the caps may have cut it short
+// like AncientRange, it returns at least one item, even if it exceeds the byte cap
+func (c ancientRangeCaps) read(r ethdb.AncientReaderOp, kind string, start, count, maxBytes uint64) ([][]byte, bool, error) {
+	cappedCount, cappedBytes := count, maxBytes
+	if c.count > 0 && cappedCount > c.count {
+		cappedCount = c.count
+	}
+	if c.bytes > 0 && (cappedBytes == 0 || cappedBytes > c.bytes) {
+		cappedBytes = c.bytes
+	}
+	items, err := r.AncientRange(kind, start, cappedCount, cappedBytes)
+	if err != nil {
+		return nil, false, err
+	}
+	n := uint64(len(items))
+	if n >= count || (n < cappedCount && cappedBytes == maxBytes) {
+		// the range was complete, or cut short by the caller's own limits
+		return items, false, nil
+	}
+	// the caps may have cut the range short, unless it reached the end of the freezer
+	ancients, err := r.Ancients()
+	if err != nil {
+		return nil, false, err
+	}
+	return items, start+n < ancients, nil
+}
+
+// readAncientOps executes the given operations against a single consistent view of the freezer
+// ancientRange operations are subject to the caps
+func readAncientOps(reader ethdb.AncientReader, caps ancientRangeCaps, ops []AncientOp) ([]AncientOpResult, error) {
+	if len(ops) > MaxAncientOps {
+		return nil, fmt.Errorf("too many ancient operations requested: %d > %d", len(ops), MaxAncientOps)
+	}
+	results := make([]AncientOpResult, len(ops))
+	err := reader.ReadAncients(func(r ethdb.AncientReaderOp) error {
+		for i, op := range ops {
+			var (
+				res = &results[i]
+				err error
+			)
+			switch op.Op {
+			case AncientOpHasAncient:
+				res.Has, err = r.HasAncient(op.Kind, op.Number)
+			case AncientOpAncient:
+				res.Value, err = r.Ancient(op.Kind, op.Number)
+			case AncientOpAncientRange:
+				res.Values, res.Truncated, err = caps.read(r, op.Kind, op.Number, op.Count, op.MaxBytes)
+			case AncientOpAncients:
+				res.Number, err = r.Ancients()
+			case AncientOpTail:
+				res.Number, err = r.Tail()
+			case AncientOpAncientSize:
+				res.Number, err = r.AncientSize(op.Kind)
+			default:
+				return fmt.Errorf("unknown ancient operation %q", op.Op)
+			}
+			if err != nil {
+				res.Error = err.Error()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -19,6 +19,10 @@ package leveldb_ethdb_rpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // APIName is the namespace used for the state diffing service API
@@ -27,46 +31,392 @@ const APIName = "leveldb"
 // APIVersion is the version of the state diffing service API
 const APIVersion = "0.0.1"
 
+// MaxManyKeys is the maximum number of keys that can be requested in a single GetMany or HasMany call
+const MaxManyKeys = 1024
+
 var (
 	errWriteNotAllowed = errors.New("write endpoints are not enabled")
 )
 
+// BatchOp is a single put or delete applied by WriteBatch
+type BatchOp struct {
+	Key    []byte `json:"key"`
+	Value  []byte `json:"value,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+}
+
+func batchOpsSize(ops []BatchOp) int {
+	size := 0
+	for _, op := range ops {
+		size += len(op.Key) + len(op.Value)
+	}
+	return size
+}
+
+// GetManyResult holds the values returned by GetMany, in the same order as the requested keys
+// Found[i] is false if the i-th key is not present, in which case Values[i] is nil
+type GetManyResult struct {
+	Values [][]byte `json:"values"`
+	Found  []bool   `json:"found"`
+}
+
 type PublicLevelDBAPI struct {
-	b *LevelDBBackend
+	b           *LevelDBBackend
+	iterators   *iteratorStore
+	snapshots   *snapshotStore
+	inspector   *inspector
+	watcher     *watcher
+	ancientCaps ancientRangeCaps
+	limiter     *RateLimiter
+}
+
+// NewPublicLevelDBAPI returns the API of the backend, its calls admitted by limiter if not nil
+func NewPublicLevelDBAPI(b *LevelDBBackend, limiter *RateLimiter) *PublicLevelDBAPI {
+	return &PublicLevelDBAPI{
+		b:           b,
+		limiter:     limiter,
+		iterators:   newIteratorStore(b.conf.MaxIterators),
+		snapshots:   newSnapshotStore(b.conf.MaxSnapshots),
+		inspector:   newInspector(b),
+		watcher:     newWatcher(b),
+		ancientCaps: ancientRangeCaps{count: b.conf.MaxAncientRangeCount, bytes: b.conf.MaxAncientRangeBytes},
+	}
 }
 
-func NewPublicLevelDBAPI(b *LevelDBBackend) *PublicLevelDBAPI {
-	return &PublicLevelDBAPI{b: b}
+// begin admits a call of the named method of this API, see RateLimiter.begin
+func (s *PublicLevelDBAPI) begin(ctx context.Context, method string) (func(), error) {
+	return s.limiter.begin(ctx, APIName+"_"+method)
 }
 
 func (s *PublicLevelDBAPI) Has(ctx context.Context, key []byte) (bool, error) {
+	done, err := s.begin(ctx, "has")
+	if err != nil {
+		return false, err
+	}
+	defer done()
 	return s.b.Has(key)
 }
 
 func (s *PublicLevelDBAPI) Get(ctx context.Context, key []byte) ([]byte, error) {
+	done, err := s.begin(ctx, "get")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	return s.b.Get(key)
 }
 
+// GetMany retrieves the values for a list of keys in a single request
+func (s *PublicLevelDBAPI) GetMany(ctx context.Context, keys [][]byte) (*GetManyResult, error) {
+	done, err := s.begin(ctx, "getMany")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if len(keys) > MaxManyKeys {
+		return nil, fmt.Errorf("too many keys requested: %d > %d", len(keys), MaxManyKeys)
+	}
+	res := &GetManyResult{
+		Values: make([][]byte, len(keys)),
+		Found:  make([]bool, len(keys)),
+	}
+	for i, key := range keys {
+		value, err := s.b.Get(key)
+		if err != nil {
+			// distinguish a missing key from a genuine read failure
+			if ok, hasErr := s.b.Has(key); hasErr != nil || ok {
+				return nil, err
+			}
+			continue
+		}
+		res.Values[i], res.Found[i] = value, true
+	}
+	return res, nil
+}
+
+// HasMany reports whether each key in a list is present in a single request
+func (s *PublicLevelDBAPI) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	done, err := s.begin(ctx, "hasMany")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if len(keys) > MaxManyKeys {
+		return nil, fmt.Errorf("too many keys requested: %d > %d", len(keys), MaxManyKeys)
+	}
+	res := make([]bool, len(keys))
+	for i, key := range keys {
+		ok, err := s.b.Has(key)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = ok
+	}
+	return res, nil
+}
+
 func (s *PublicLevelDBAPI) HasAncient(ctx context.Context, kind string, number uint64) (bool, error) {
+	done, err := s.begin(ctx, "hasAncient")
+	if err != nil {
+		return false, err
+	}
+	defer done()
 	return s.b.HasAncient(kind, number)
 }
 
 func (s *PublicLevelDBAPI) Ancient(ctx context.Context, kind string, number uint64) ([]byte, error) {
+	done, err := s.begin(ctx, "ancient")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	return s.b.Ancient(kind, number)
 }
 
+// AncientRange returns a range of freezer items within the server's caps on count and bytes, cut short by them
+// without telling the caller, as it always has; callers that need to know use AncientRangePage
 func (s *PublicLevelDBAPI) AncientRange(ctx context.Context, kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	return s.b.AncientRange(kind, start, count, maxBytes)
+	done, err := s.begin(ctx, "ancientRange")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	items, _, err := s.ancientCaps.read(s.b, kind, start, count, maxBytes)
+	return items, err
+}
+
+// AncientRangePage returns a range of freezer items within the server's caps on count and bytes,
+// and whether the caps cut it short
+func (s *PublicLevelDBAPI) AncientRangePage(ctx context.Context, kind string, start, count, maxBytes uint64) (*AncientRangeResult, error) {
+	done, err := s.begin(ctx, "ancientRangePage")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	items, truncated, err := s.ancientCaps.read(s.b, kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &AncientRangeResult{Items: items, Truncated: truncated}, nil
 }
 
 func (s *PublicLevelDBAPI) Ancients(ctx context.Context) (uint64, error) {
+	done, err := s.begin(ctx, "ancients")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
 	return s.b.Ancients()
 }
 
+func (s *PublicLevelDBAPI) Tail(ctx context.Context) (uint64, error) {
+	done, err := s.begin(ctx, "tail")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	return s.b.Tail()
+}
+
 func (s *PublicLevelDBAPI) AncientSize(ctx context.Context, kind string) (uint64, error) {
+	done, err := s.begin(ctx, "ancientSize")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
 	return s.b.AncientSize(kind)
 }
 
+// ReadAncients executes a list of freezer reads against a single consistent view of the freezer
+func (s *PublicLevelDBAPI) ReadAncients(ctx context.Context, ops []AncientOp) ([]AncientOpResult, error) {
+	done, err := s.begin(ctx, "readAncients")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return readAncientOps(s.b, s.ancientCaps, ops)
+}
+
+// AncientDatadir returns the path of the freezer directory on the server
+func (s *PublicLevelDBAPI) AncientDatadir(ctx context.Context) (string, error) {
+	done, err := s.begin(ctx, "ancientDatadir")
+	if err != nil {
+		return "", err
+	}
+	defer done()
+	return s.b.AncientDatadir()
+}
+
 func (s *PublicLevelDBAPI) Stat(ctx context.Context, property string) (string, error) {
+	done, err := s.begin(ctx, "stat")
+	if err != nil {
+		return "", err
+	}
+	defer done()
 	return s.b.Stat(property)
 }
+
+// NewIterator opens a server-side iterator over the keys with the given prefix, starting at start
+// and returns the ID used to page through it with IteratorNext
+func (s *PublicLevelDBAPI) NewIterator(ctx context.Context, prefix []byte, start []byte) (rpc.ID, error) {
+	done, err := s.begin(ctx, "newIterator")
+	if err != nil {
+		return "", err
+	}
+	defer done()
+	return s.iterators.open(s.b.NewIterator(prefix, start))
+}
+
+// IteratorNext returns the next page of at most limit key/value pairs from the iterator with the given ID
+// the iterator is released by the server once the returned page is marked done
+func (s *PublicLevelDBAPI) IteratorNext(ctx context.Context, id rpc.ID, limit int) (*IteratorPage, error) {
+	done, err := s.begin(ctx, "iteratorNext")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return s.iterators.next(ctx, id, limit)
+}
+
+// ReleaseIterator releases the iterator with the given ID
+func (s *PublicLevelDBAPI) ReleaseIterator(ctx context.Context, id rpc.ID) error {
+	done, err := s.begin(ctx, "releaseIterator")
+	if err != nil {
+		return err
+	}
+	defer done()
+	s.iterators.release(id)
+	return nil
+}
+
+// NewSnapshot takes a snapshot of the current state of the database and returns the ID used to read from it
+// the snapshot is released by the server once it goes unused for ttl seconds (0 selects DefaultSnapshotTTL)
+func (s *PublicLevelDBAPI) NewSnapshot(ctx context.Context, ttl uint64) (rpc.ID, error) {
+	done, err := s.begin(ctx, "newSnapshot")
+	if err != nil {
+		return "", err
+	}
+	defer done()
+	// read the write generation first, so that a write racing with the snapshot can only make it look stale
+	gen := s.b.WriteGeneration()
+	snap, err := s.b.NewSnapshot()
+	if err != nil {
+		return "", err
+	}
+	return s.snapshots.add(snap, gen, time.Duration(ttl)*time.Second)
+}
+
+// ReleaseSnapshot releases the snapshot with the given ID
+func (s *PublicLevelDBAPI) ReleaseSnapshot(ctx context.Context, id rpc.ID) error {
+	done, err := s.begin(ctx, "releaseSnapshot")
+	if err != nil {
+		return err
+	}
+	defer done()
+	s.snapshots.release(id)
+	return nil
+}
+
+// SnapshotHas retrieves if a key is present in the snapshot with the given ID
+func (s *PublicLevelDBAPI) SnapshotHas(ctx context.Context, id rpc.ID, key []byte) (bool, error) {
+	done, err := s.begin(ctx, "snapshotHas")
+	if err != nil {
+		return false, err
+	}
+	defer done()
+	rs, err := s.snapshots.get(id)
+	if err != nil {
+		return false, err
+	}
+	return rs.snap.Has(key)
+}
+
+// SnapshotGet retrieves the given key if it's present in the snapshot with the given ID
+func (s *PublicLevelDBAPI) SnapshotGet(ctx context.Context, id rpc.ID, key []byte) ([]byte, error) {
+	done, err := s.begin(ctx, "snapshotGet")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	rs, err := s.snapshots.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return rs.snap.Get(key)
+}
+
+// SnapshotNewIterator opens a server-side iterator over the snapshot with the given ID
+// the returned ID is paged through with IteratorNext and released with ReleaseIterator
+// iterators can only be opened as long as the database hasn't been written to through the server since the snapshot
+// was taken, as the engines' snapshots can't be iterated; errSnapshotStale is returned otherwise
+func (s *PublicLevelDBAPI) SnapshotNewIterator(ctx context.Context, id rpc.ID, prefix []byte, start []byte) (rpc.ID, error) {
+	done, err := s.begin(ctx, "snapshotNewIterator")
+	if err != nil {
+		return "", err
+	}
+	defer done()
+	rs, err := s.snapshots.get(id)
+	if err != nil {
+		return "", err
+	}
+	it, err := s.b.NewIteratorAt(rs.snap, rs.gen, prefix, start)
+	if err != nil {
+		return "", err
+	}
+	return s.iterators.open(it)
+}
+
+// Put inserts the given value into the database, if writes are enabled
+func (s *PublicLevelDBAPI) Put(ctx context.Context, key []byte, value []byte) error {
+	done, err := s.begin(ctx, "put")
+	if err != nil {
+		return err
+	}
+	defer done()
+	return s.b.Put(key, value)
+}
+
+// Delete removes the key from the database, if writes are enabled
+func (s *PublicLevelDBAPI) Delete(ctx context.Context, key []byte) error {
+	done, err := s.begin(ctx, "delete")
+	if err != nil {
+		return err
+	}
+	defer done()
+	return s.b.Delete(key)
+}
+
+// WriteBatch atomically applies a list of puts and deletes to the database, if writes are enabled
+func (s *PublicLevelDBAPI) WriteBatch(ctx context.Context, ops []BatchOp) error {
+	done, err := s.begin(ctx, "writeBatch")
+	if err != nil {
+		return err
+	}
+	defer done()
+	batch := s.b.NewBatchWithSize(batchOpsSize(ops))
+	if batch == nil {
+		return errWriteNotAllowed
+	}
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = batch.Delete(op.Key)
+		} else {
+			err = batch.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// Sync flushes all in-memory ancient store data to disk, if writes are enabled
+func (s *PublicLevelDBAPI) Sync(ctx context.Context) error {
+	done, err := s.begin(ctx, "sync")
+	if err != nil {
+		return err
+	}
+	defer done()
+	return s.b.Sync()
+}
@@ -0,0 +1,118 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetManyHasMany(t *testing.T) {
+	api := NewPublicLevelDBAPI(newTestBackend(t, newTestConfig(t, map[string]string{"a": "1", "c": "3"})), nil)
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+
+	res, err := api.GetMany(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "", "3", ""}
+	for i := range keys {
+		if res.Found[i] != (want[i] != "") || string(res.Values[i]) != want[i] {
+			t.Errorf("key %s: have %q (found %v), want %q", keys[i], res.Values[i], res.Found[i], want[i])
+		}
+	}
+	found, err := api.HasMany(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range keys {
+		if found[i] != (want[i] != "") {
+			t.Errorf("key %s: have found %v", keys[i], found[i])
+		}
+	}
+}
+
+func TestManyKeysLimit(t *testing.T) {
+	api := NewPublicLevelDBAPI(newTestBackend(t, newTestConfig(t, nil)), nil)
+	keys := make([][]byte, MaxManyKeys)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("k%d", i))
+	}
+	if _, err := api.GetMany(context.Background(), keys); err != nil {
+		t.Fatalf("getMany at the limit: %v", err)
+	}
+	if _, err := api.HasMany(context.Background(), keys); err != nil {
+		t.Fatalf("hasMany at the limit: %v", err)
+	}
+
+	keys = append(keys, []byte("one too many"))
+	if _, err := api.GetMany(context.Background(), keys); err == nil || !strings.Contains(err.Error(), "too many keys requested") {
+		t.Fatalf("getMany over the limit: have %v", err)
+	}
+	if _, err := api.HasMany(context.Background(), keys); err == nil || !strings.Contains(err.Error(), "too many keys requested") {
+		t.Fatalf("hasMany over the limit: have %v", err)
+	}
+}
+
+// cancelAfter is a context whose Err reports it cancelled once it has been checked n times
+type cancelAfter struct {
+	context.Context
+	n, checks int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.checks++; c.checks > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+// TestIteratorNextCancelled checks that a page read by IteratorNext stops once the request is cancelled,
+// and that the iterator is released rather than carrying on past the pairs read so far
+func TestIteratorNextCancelled(t *testing.T) {
+	entries := make(map[string]string, 1000)
+	for i := 0; i < 1000; i++ {
+		entries[fmt.Sprintf("k%04d", i)] = "v"
+	}
+	api := NewPublicLevelDBAPI(newTestBackend(t, newTestConfig(t, entries)), nil)
+	id, err := api.NewIterator(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cancelled after the request was admitted, while the page is being read
+	ctx := &cancelAfter{Context: context.Background(), n: 2}
+	if _, err := api.IteratorNext(ctx, id, 1000); !errors.Is(err, context.Canceled) {
+		t.Fatalf("have error %v, want the page to be cancelled", err)
+	}
+	if _, err := api.IteratorNext(context.Background(), id, 1000); !errors.Is(err, errIteratorNotFound) {
+		t.Fatalf("have error %v after the cancelled page, want the iterator released", err)
+	}
+
+	// a request cancelled before it is served doesn't read a page either
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if id, err = api.NewIterator(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.IteratorNext(cancelled, id, 1000); !errors.Is(err, context.Canceled) {
+		t.Fatalf("have error %v, want the page to be cancelled", err)
+	}
+}
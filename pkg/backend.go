@@ -18,79 +18,201 @@ package leveldb_ethdb_rpc
 
 import (
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
-	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
-	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	log "github.com/sirupsen/logrus"
 )
 
-var errNotSupported = errors.New("this operation is not supported")
+var (
+	errNotSupported  = errors.New("this operation is not supported")
+	errSnapshotStale = errors.New("the database has changed since the snapshot was taken")
+)
 var _ ethdb.Database = &LevelDBBackend{}
 
 // NewLevelDBBackend creates a new levelDB RPC server backend
+// the key-value store is opened with the configured (or detected) storage engine,
+// and only opened read-write if writes are enabled in the config
+// in follow mode the read-only database is reopened in the background to pick up the writes of the owning process
 func NewLevelDBBackend(conf *Config) (*LevelDBBackend, error) {
-	db, err := leveldb.New(conf.FilePath, conf.Cache, conf.Handles, conf.Namespace, true)
-	if err != nil {
-		return nil, err
+	if conf.Follow && conf.WriteEnabled {
+		return nil, errors.New("follow mode requires the database to be opened read-only")
 	}
-	frdb, err := rawdb.NewDatabaseWithFreezer(db, conf.FreezerPath, conf.Namespace, true)
+	readonly := !conf.WriteEnabled
+	// read before opening, so that changes made while opening are picked up by the first reopen
+	var state fileState
+	if conf.Follow {
+		var err error
+		if state, err = readFileState(conf.FilePath, conf.FreezerPath); err != nil {
+			log.WithError(err).Debug("failed to read the state of the database files")
+		}
+	}
+	view, engine, err := openView(conf, "", readonly)
 	if err != nil {
-		db.Close()
 		return nil, err
 	}
-	return &LevelDBBackend{
-		ethDB:   frdb,
-		levelDB: db,
-	}, nil
+	if conf.Follow && engine != EngineLevelDB {
+		// pebble locks the database exclusively even when read-only, so a second view can't be opened next to the first
+		view.release()
+		return nil, fmt.Errorf("follow mode is not supported by the %s engine", engine)
+	}
+	log.Infof("using %s as the backing database at %s", engine, conf.FilePath)
+	backend := &LevelDBBackend{
+		conf:         conf,
+		view:         view,
+		engine:       engine,
+		writeEnabled: conf.WriteEnabled,
+	}
+	if conf.Follow {
+		backend.followQuit = make(chan struct{})
+		backend.followDone = make(chan struct{})
+		go backend.follow(conf.FollowInterval, state)
+		log.Infof("following the database at %s", conf.FilePath)
+	}
+	return backend, nil
 }
 
 type LevelDBBackend struct {
-	ethDB   ethdb.Database
-	levelDB *leveldb.Database
+	conf         *Config
+	engine       string
+	writeEnabled bool
+
+	// viewLock guards view, the currently open database, which is swapped out when reopened in follow mode
+	viewLock sync.RWMutex
+	view     *dbView
+
+	// writeLock is held while writing so that snapshot iterators can be opened against an unchanged database
+	writeLock sync.RWMutex
+	// writeGen is bumped by every write
+	writeGen uint64
+	// closed is set once the database is closed, guarded by writeLock
+	closed bool
+
+	// followQuit and followDone stop and wait for the follow loop, nil unless in follow mode
+	followQuit chan struct{}
+	followDone chan struct{}
+	followStop sync.Once
+	// retiredViews counts the views swapped out by a reopen that are still held open by their users
+	retiredViews atomic.Int64
+}
+
+// acquireView returns the currently open database; the caller must release it when done
+func (s *LevelDBBackend) acquireView() *dbView {
+	s.viewLock.RLock()
+	defer s.viewLock.RUnlock()
+	s.view.refs.Add(1)
+	return s.view
+}
+
+// Engine returns the storage engine backing the key-value store
+func (s *LevelDBBackend) Engine() string {
+	return s.engine
 }
 
 func (s *LevelDBBackend) Has(key []byte) (bool, error) {
-	return s.ethDB.Has(key)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Has(key)
 }
 
 func (s *LevelDBBackend) Get(key []byte) ([]byte, error) {
-	return s.ethDB.Get(key)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Get(key)
 }
 
 func (s *LevelDBBackend) HasAncient(kind string, number uint64) (bool, error) {
-	return s.ethDB.HasAncient(kind, number)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.HasAncient(kind, number)
 }
 
 func (s *LevelDBBackend) Ancient(kind string, number uint64) ([]byte, error) {
-	return s.ethDB.Ancient(kind, number)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Ancient(kind, number)
 }
 
 func (s *LevelDBBackend) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	return s.ethDB.AncientRange(kind, start, count, maxBytes)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.AncientRange(kind, start, count, maxBytes)
 }
 
 func (s *LevelDBBackend) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
-	return s.ethDB.ReadAncients(fn)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.ReadAncients(fn)
 }
 
 func (s *LevelDBBackend) Ancients() (uint64, error) {
-	return s.ethDB.Ancients()
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Ancients()
 }
 
 func (s *LevelDBBackend) Tail() (uint64, error) {
-	return s.ethDB.Tail()
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Tail()
 }
 
 func (s *LevelDBBackend) AncientSize(kind string) (uint64, error) {
-	return s.ethDB.AncientSize(kind)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.AncientSize(kind)
 }
 
-func (s *LevelDBBackend) Put(_ []byte, _ []byte) error {
-	return errWriteNotAllowed
+func (s *LevelDBBackend) Put(key []byte, value []byte) error {
+	if !s.writeEnabled {
+		return errWriteNotAllowed
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.writeGen++
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Put(key, value)
 }
 
-func (s *LevelDBBackend) Delete(_ []byte) error {
-	return errWriteNotAllowed
+func (s *LevelDBBackend) Delete(key []byte) error {
+	if !s.writeEnabled {
+		return errWriteNotAllowed
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.writeGen++
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Delete(key)
+}
+
+// WriteGeneration returns a counter that changes every time the database is written to
+func (s *LevelDBBackend) WriteGeneration() uint64 {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+	return s.writeGen
+}
+
+// NewIteratorAt creates an iterator over the view the snapshot was taken on, as long as the database hasn't been
+// written to since the given write generation, so that it yields the same content as the snapshot
+// the view is left as it was by a reopen in follow mode, so the snapshot can still be iterated after one
+func (s *LevelDBBackend) NewIteratorAt(snap ethdb.Snapshot, gen uint64, prefix []byte, start []byte) (ethdb.Iterator, error) {
+	vs, ok := snap.(*viewSnapshot)
+	if !ok {
+		return nil, errNotSupported
+	}
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+	if s.writeGen != gen {
+		return nil, errSnapshotStale
+	}
+	if !vs.view.tryAcquire() {
+		return nil, errSnapshotNotFound
+	}
+	return &viewIterator{Iterator: vs.view.ethDB.NewIterator(prefix, start), view: vs.view}, nil
 }
 
 func (s *LevelDBBackend) ModifyAncients(f func(ethdb.AncientWriteOp) error) (int64, error) {
@@ -106,42 +228,105 @@ func (s *LevelDBBackend) TruncateTail(n uint64) (uint64, error) {
 }
 
 func (s *LevelDBBackend) Sync() error {
-	return errWriteNotAllowed
+	if !s.writeEnabled {
+		return errWriteNotAllowed
+	}
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Sync()
 }
 
+// NewBatch returns nil unless writes are enabled
+// the database is never reopened while writes are enabled, so the batch can hold on to the current view
 func (s *LevelDBBackend) NewBatch() ethdb.Batch {
-	return nil
+	if !s.writeEnabled {
+		return nil
+	}
+	v := s.acquireView()
+	defer v.release()
+	return &backendBatch{Batch: v.ethDB.NewBatch(), b: s}
 }
 
 func (d *LevelDBBackend) NewBatchWithSize(size int) ethdb.Batch {
-	return nil
+	if !d.writeEnabled {
+		return nil
+	}
+	v := d.acquireView()
+	defer v.release()
+	return &backendBatch{Batch: v.ethDB.NewBatchWithSize(size), b: d}
+}
+
+// backendBatch is a batch whose writes are accounted for like any other write to the backend
+type backendBatch struct {
+	ethdb.Batch
+	b *LevelDBBackend
 }
 
-func (s *LevelDBBackend) NewIterator(_ []byte, _ []byte) ethdb.Iterator {
-	return nil
+func (b *backendBatch) Write() error {
+	b.b.writeLock.Lock()
+	defer b.b.writeLock.Unlock()
+	b.b.writeGen++
+	return b.Batch.Write()
+}
+
+// NewIterator creates an iterator over the current database, which is kept open until the iterator is released
+func (s *LevelDBBackend) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	v := s.acquireView()
+	return &viewIterator{Iterator: v.ethDB.NewIterator(prefix, start), view: v}
 }
 
 func (s *LevelDBBackend) Stat(property string) (string, error) {
-	return s.ethDB.Stat(property)
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Stat(property)
 }
 
-func (s *LevelDBBackend) Compact(_ []byte, _ []byte) error {
-	return errWriteNotAllowed
+func (s *LevelDBBackend) Compact(start []byte, limit []byte) error {
+	if !s.writeEnabled {
+		return errWriteNotAllowed
+	}
+	v := s.acquireView()
+	defer v.release()
+	return v.ethDB.Compact(start, limit)
 }
 
+// Close stops following the database and closes the key-value store and the freezer
+// it waits for in-flight writes to finish, and is a no-op if the backend is already closed
+// the database is only closed once the iterators and snapshots still open on it are released
 func (s *LevelDBBackend) Close() error {
-	return errWriteNotAllowed
+	s.followStop.Do(func() {
+		if s.followQuit != nil {
+			close(s.followQuit)
+			<-s.followDone
+		}
+	})
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.view.release()
 }
 
 func (s *LevelDBBackend) MigrateTable(string, func([]byte) ([]byte, error)) error {
 	return errWriteNotAllowed
 }
 
+// NewSnapshot creates a snapshot of the current database, which is kept open until the snapshot is released
 func (s *LevelDBBackend) NewSnapshot() (ethdb.Snapshot, error) {
-	return s.ethDB.NewSnapshot()
+	v := s.acquireView()
+	snap, err := v.ethDB.NewSnapshot()
+	if err != nil {
+		v.release()
+		return nil, err
+	}
+	return &viewSnapshot{Snapshot: snap, view: v}, nil
 }
 
-// AncientDatadir returns an error as we don't have a backing chain freezer.
+// AncientDatadir returns the path of the backing chain freezer.
 func (d *LevelDBBackend) AncientDatadir() (string, error) {
-	return "", errNotSupported
+	v := d.acquireView()
+	defer v.release()
+	return v.ethDB.AncientDatadir()
 }
@@ -0,0 +1,205 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// BinaryPath is the HTTP path the binary transport is served on, next to the JSON-RPC handler
+	BinaryPath = "/rlp"
+	// BinaryContentType is the content type of binary transport requests and responses
+	BinaryContentType = "application/x-rlp"
+
+	// The methods of PublicLevelDBAPI available over the binary transport
+	BinaryMethodGet              = "get"
+	BinaryMethodGetMany          = "getMany"
+	BinaryMethodAncient          = "ancient"
+	BinaryMethodAncientRange     = "ancientRange"
+	BinaryMethodAncientRangePage = "ancientRangePage"
+
+	// maxBinaryRequestSize mirrors the request body limit of the JSON-RPC HTTP handler
+	maxBinaryRequestSize = 5 * 1024 * 1024
+)
+
+// binaryMethods are the methods served by the binary transport; requests for any other method are timed
+// under a single name, so that clients can't register new metrics
+var binaryMethods = map[string]bool{
+	BinaryMethodGet:              true,
+	BinaryMethodGetMany:          true,
+	BinaryMethodAncient:          true,
+	BinaryMethodAncientRange:     true,
+	BinaryMethodAncientRangePage: true,
+}
+
+// BinaryRequest is the RLP encoded body of a binary transport request
+// Params holds the RLP encoding of the parameter struct matching the method
+type BinaryRequest struct {
+	Method string
+	Params rlp.RawValue
+}
+
+// BinaryResponse is the RLP encoded body of a binary transport response
+// Result holds the RLP encoding of the method's result, unless Error is set
+type BinaryResponse struct {
+	Error  string
+	Result rlp.RawValue
+}
+
+// The parameters and results of the binary transport methods
+type (
+	BinaryGetParams struct {
+		Key []byte
+	}
+	BinaryGetManyParams struct {
+		Keys [][]byte
+	}
+	BinaryGetManyResult struct {
+		Values [][]byte
+		Found  []bool
+	}
+	BinaryAncientParams struct {
+		Kind   string
+		Number uint64
+	}
+	BinaryAncientRangeParams struct {
+		Kind     string
+		Start    uint64
+		Count    uint64
+		MaxBytes uint64
+	}
+	BinaryAncientRangeResult struct {
+		Items     [][]byte
+		Truncated bool
+	}
+)
+
+// BinaryTransport returns the HTTP path of the binary transport, which serves the byte-heavy methods of this API
+// with RLP framing instead of JSON, for clients that negotiate it; it is below the path of a named database
+func (s *PublicLevelDBAPI) BinaryTransport(ctx context.Context) (string, error) {
+	done, err := s.begin(ctx, "binaryTransport")
+	if err != nil {
+		return "", err
+	}
+	defer done()
+	return DatabasePath(s.b.conf.Name) + BinaryPath, nil
+}
+
+// binaryHandler serves the byte-heavy PublicLevelDBAPI methods over HTTP with RLP encoded requests and responses
+type binaryHandler struct {
+	api *PublicLevelDBAPI
+}
+
+// NewBinaryHandler returns the HTTP handler of the binary transport for the given API
+func NewBinaryHandler(api *PublicLevelDBAPI) http.Handler {
+	return &binaryHandler{api: api}
+}
+
+func (h *binaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBinaryRequestSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBinaryRequestSize {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var req BinaryRequest
+	if err := rlp.DecodeBytes(body, &req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var resp BinaryResponse
+	start := time.Now()
+	result, err := h.call(r.Context(), &req)
+	updateBinaryServeTime(req.Method, err == nil, time.Since(start))
+	if err == nil {
+		resp.Result, err = rlp.EncodeToBytes(result)
+	}
+	if err != nil {
+		resp.Error, resp.Result = err.Error(), rlp.EmptyString
+	}
+	enc, err := rlp.EncodeToBytes(&resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", BinaryContentType)
+	if _, err := w.Write(enc); err != nil {
+		log.WithError(err).Debug("failed to write binary transport response")
+	}
+}
+
+// call decodes the parameters of the request and dispatches it to the API
+func (h *binaryHandler) call(ctx context.Context, req *BinaryRequest) (interface{}, error) {
+	switch req.Method {
+	case BinaryMethodGet:
+		var params BinaryGetParams
+		if err := rlp.DecodeBytes(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return h.api.Get(ctx, params.Key)
+	case BinaryMethodGetMany:
+		var params BinaryGetManyParams
+		if err := rlp.DecodeBytes(req.Params, &params); err != nil {
+			return nil, err
+		}
+		res, err := h.api.GetMany(ctx, params.Keys)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryGetManyResult{Values: res.Values, Found: res.Found}, nil
+	case BinaryMethodAncient:
+		var params BinaryAncientParams
+		if err := rlp.DecodeBytes(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return h.api.Ancient(ctx, params.Kind, params.Number)
+	case BinaryMethodAncientRange:
+		var params BinaryAncientRangeParams
+		if err := rlp.DecodeBytes(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return h.api.AncientRange(ctx, params.Kind, params.Start, params.Count, params.MaxBytes)
+	case BinaryMethodAncientRangePage:
+		var params BinaryAncientRangeParams
+		if err := rlp.DecodeBytes(req.Params, &params); err != nil {
+			return nil, err
+		}
+		res, err := h.api.AncientRangePage(ctx, params.Kind, params.Start, params.Count, params.MaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryAncientRangeResult{Items: res.Items, Truncated: res.Truncated}, nil
+	default:
+		return nil, fmt.Errorf("the method %s does not exist/is not available over the binary transport", req.Method)
+	}
+}
@@ -0,0 +1,238 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ChainDataAPIName is the namespace used for the typed chain data API
+const ChainDataAPIName = "chaindata"
+
+// ChainDataAPIVersion is the version of the typed chain data API
+const ChainDataAPIVersion = "0.0.1"
+
+// Body is the JSON representation of a block body
+type Body struct {
+	Transactions []*types.Transaction `json:"transactions"`
+	Uncles       []*types.Header      `json:"uncles"`
+	Withdrawals  []*types.Withdrawal  `json:"withdrawals,omitempty"`
+}
+
+// HeadPointers holds the hashes of the chain heads tracked by geth
+type HeadPointers struct {
+	HeadHeader    common.Hash `json:"headHeader"`
+	HeadBlock     common.Hash `json:"headBlock"`
+	HeadFastBlock common.Hash `json:"headFastBlock"`
+	Finalized     common.Hash `json:"finalized"`
+}
+
+// PublicChainDataAPI decodes chain data using the rawdb accessors, so that clients don't need to know geth's key schema
+// the accessors transparently read from the freezer or the key-value store depending on where the data lives
+// methods return nil if the requested data is not present
+type PublicChainDataAPI struct {
+	b       *LevelDBBackend
+	limiter *RateLimiter
+}
+
+// NewPublicChainDataAPI returns the chain data API of the backend, its calls admitted by limiter if not nil
+func NewPublicChainDataAPI(b *LevelDBBackend, limiter *RateLimiter) *PublicChainDataAPI {
+	return &PublicChainDataAPI{b: b, limiter: limiter}
+}
+
+// begin admits a call of the named method of this API, see RateLimiter.begin
+func (s *PublicChainDataAPI) begin(ctx context.Context, method string) (func(), error) {
+	return s.limiter.begin(ctx, ChainDataAPIName+"_"+method)
+}
+
+// CanonicalHash returns the hash of the canonical block at the given number
+func (s *PublicChainDataAPI) CanonicalHash(ctx context.Context, number uint64) (*common.Hash, error) {
+	done, err := s.begin(ctx, "canonicalHash")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	hash := rawdb.ReadCanonicalHash(s.b, number)
+	if hash == (common.Hash{}) {
+		return nil, nil
+	}
+	return &hash, nil
+}
+
+// HeaderNumber returns the number of the block with the given hash
+func (s *PublicChainDataAPI) HeaderNumber(ctx context.Context, hash common.Hash) (*hexutil.Uint64, error) {
+	done, err := s.begin(ctx, "headerNumber")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return (*hexutil.Uint64)(rawdb.ReadHeaderNumber(s.b, hash)), nil
+}
+
+// HeaderByNumber returns the header of the canonical block at the given number
+func (s *PublicChainDataAPI) HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	done, err := s.begin(ctx, "headerByNumber")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	hash := rawdb.ReadCanonicalHash(s.b, number)
+	if hash == (common.Hash{}) {
+		return nil, nil
+	}
+	return rawdb.ReadHeader(s.b, hash, number), nil
+}
+
+// HeaderByHash returns the header of the block with the given hash
+func (s *PublicChainDataAPI) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	done, err := s.begin(ctx, "headerByHash")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return s.headerByHash(hash), nil
+}
+
+func (s *PublicChainDataAPI) headerByHash(hash common.Hash) *types.Header {
+	number := rawdb.ReadHeaderNumber(s.b, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(s.b, hash, *number)
+}
+
+// BodyByNumber returns the body of the canonical block at the given number
+func (s *PublicChainDataAPI) BodyByNumber(ctx context.Context, number uint64) (*Body, error) {
+	done, err := s.begin(ctx, "bodyByNumber")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	hash := rawdb.ReadCanonicalHash(s.b, number)
+	if hash == (common.Hash{}) {
+		return nil, nil
+	}
+	return s.body(hash, number), nil
+}
+
+// BodyByHash returns the body of the block with the given hash
+func (s *PublicChainDataAPI) BodyByHash(ctx context.Context, hash common.Hash) (*Body, error) {
+	done, err := s.begin(ctx, "bodyByHash")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	number := rawdb.ReadHeaderNumber(s.b, hash)
+	if number == nil {
+		return nil, nil
+	}
+	return s.body(hash, *number), nil
+}
+
+func (s *PublicChainDataAPI) body(hash common.Hash, number uint64) *Body {
+	body := rawdb.ReadBody(s.b, hash, number)
+	if body == nil {
+		return nil
+	}
+	return &Body{
+		Transactions: body.Transactions,
+		Uncles:       body.Uncles,
+		Withdrawals:  body.Withdrawals,
+	}
+}
+
+// ReceiptsByNumber returns the receipts of the canonical block at the given number
+func (s *PublicChainDataAPI) ReceiptsByNumber(ctx context.Context, number uint64) (types.Receipts, error) {
+	done, err := s.begin(ctx, "receiptsByNumber")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	hash := rawdb.ReadCanonicalHash(s.b, number)
+	if hash == (common.Hash{}) {
+		return nil, nil
+	}
+	return s.receipts(hash, number), nil
+}
+
+// ReceiptsByHash returns the receipts of the block with the given hash
+func (s *PublicChainDataAPI) ReceiptsByHash(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	done, err := s.begin(ctx, "receiptsByHash")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	number := rawdb.ReadHeaderNumber(s.b, hash)
+	if number == nil {
+		return nil, nil
+	}
+	return s.receipts(hash, *number), nil
+}
+
+// receipts reads the receipts of a block, deriving their metadata fields if the chain config is available
+func (s *PublicChainDataAPI) receipts(hash common.Hash, number uint64) types.Receipts {
+	header := rawdb.ReadHeader(s.b, hash, number)
+	config := rawdb.ReadChainConfig(s.b, rawdb.ReadCanonicalHash(s.b, 0))
+	if header == nil || config == nil {
+		return rawdb.ReadRawReceipts(s.b, hash, number)
+	}
+	return rawdb.ReadReceipts(s.b, hash, number, header.Time, config)
+}
+
+// TotalDifficulty returns the total difficulty of the block with the given hash
+func (s *PublicChainDataAPI) TotalDifficulty(ctx context.Context, hash common.Hash) (*hexutil.Big, error) {
+	done, err := s.begin(ctx, "totalDifficulty")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	number := rawdb.ReadHeaderNumber(s.b, hash)
+	if number == nil {
+		return nil, nil
+	}
+	return (*hexutil.Big)(rawdb.ReadTd(s.b, hash, *number)), nil
+}
+
+// HeadPointers returns the hashes of the chain heads tracked by geth
+func (s *PublicChainDataAPI) HeadPointers(ctx context.Context) (*HeadPointers, error) {
+	done, err := s.begin(ctx, "headPointers")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return &HeadPointers{
+		HeadHeader:    rawdb.ReadHeadHeaderHash(s.b),
+		HeadBlock:     rawdb.ReadHeadBlockHash(s.b),
+		HeadFastBlock: rawdb.ReadHeadFastBlockHash(s.b),
+		Finalized:     rawdb.ReadFinalizedBlockHash(s.b),
+	}, nil
+}
+
+// HeadBlockHeader returns the header of the current head block
+func (s *PublicChainDataAPI) HeadBlockHeader(ctx context.Context) (*types.Header, error) {
+	done, err := s.begin(ctx, "headBlockHeader")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return s.headerByHash(rawdb.ReadHeadBlockHash(s.b)), nil
+}
@@ -0,0 +1,201 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testChain is a genesis block and a block holding a transaction, written with rawdb
+type testChain struct {
+	genesis, block *types.Block
+	receipts       types.Receipts
+}
+
+// newTestChain writes the test chain, and the chain config of its genesis, to the database of conf
+func newTestChain(t *testing.T, conf *Config) *testChain {
+	t.Helper()
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: EngineLevelDB, Directory: conf.FilePath, AncientsDirectory: conf.FreezerPath, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), GasLimit: 5000000})
+	to := common.HexToAddress("0x01")
+	tx := types.NewTx(&types.LegacyTx{Nonce: 0, To: &to, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(1)})
+	receipt := &types.Receipt{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, GasUsed: 21000, Logs: []*types.Log{}}
+	block := types.NewBlock(&types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Difficulty: big.NewInt(1), GasLimit: 5000000, Time: 10},
+		&types.Body{Transactions: types.Transactions{tx}}, types.Receipts{receipt}, trie.NewStackTrie(nil))
+
+	rawdb.WriteChainConfig(db, genesis.Hash(), params.AllEthashProtocolChanges)
+	for i, b := range []*types.Block{genesis, block} {
+		rawdb.WriteBlock(db, b)
+		rawdb.WriteCanonicalHash(db, b.Hash(), b.NumberU64())
+		rawdb.WriteTd(db, b.Hash(), b.NumberU64(), big.NewInt(int64(i+1)))
+	}
+	rawdb.WriteReceipts(db, genesis.Hash(), 0, nil)
+	rawdb.WriteReceipts(db, block.Hash(), 1, types.Receipts{receipt})
+	rawdb.WriteHeadHeaderHash(db, block.Hash())
+	rawdb.WriteHeadBlockHash(db, block.Hash())
+	return &testChain{genesis: genesis, block: block, receipts: types.Receipts{receipt}}
+}
+
+// newTestChainDataClient serves the chaindata namespace of a database holding the test chain in process
+func newTestChainDataClient(t *testing.T) (*testChain, *rpc.Client) {
+	t.Helper()
+	conf := newTestConfig(t, nil)
+	chain := newTestChain(t, conf)
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(ChainDataAPIName, NewPublicChainDataAPI(newTestBackend(t, conf), nil)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(srv)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Stop()
+	})
+	return chain, client
+}
+
+func TestChainDataHeaders(t *testing.T) {
+	chain, client := newTestChainDataClient(t)
+	ctx := context.Background()
+
+	for _, block := range []*types.Block{chain.genesis, chain.block} {
+		var hash *common.Hash
+		if err := client.CallContext(ctx, &hash, "chaindata_canonicalHash", block.NumberU64()); err != nil {
+			t.Fatal(err)
+		}
+		if hash == nil || *hash != block.Hash() {
+			t.Fatalf("block %d: have canonical hash %v, want %v", block.NumberU64(), hash, block.Hash())
+		}
+		var number *hexutil.Uint64
+		if err := client.CallContext(ctx, &number, "chaindata_headerNumber", block.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if number == nil || uint64(*number) != block.NumberU64() {
+			t.Fatalf("block %d: have number %v", block.NumberU64(), number)
+		}
+		for _, call := range []struct {
+			method string
+			arg    interface{}
+		}{{"chaindata_headerByNumber", block.NumberU64()}, {"chaindata_headerByHash", block.Hash()}} {
+			var header *types.Header
+			if err := client.CallContext(ctx, &header, call.method, call.arg); err != nil {
+				t.Fatal(err)
+			}
+			if header == nil || header.Hash() != block.Hash() {
+				t.Fatalf("%s(%v): have header %v, want block %d", call.method, call.arg, header, block.NumberU64())
+			}
+		}
+	}
+
+	var td *hexutil.Big
+	if err := client.CallContext(ctx, &td, "chaindata_totalDifficulty", chain.block.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if td == nil || td.ToInt().Int64() != 2 {
+		t.Fatalf("have total difficulty %v, want 2", td)
+	}
+	var heads *HeadPointers
+	if err := client.CallContext(ctx, &heads, "chaindata_headPointers"); err != nil {
+		t.Fatal(err)
+	}
+	if heads.HeadHeader != chain.block.Hash() || heads.HeadBlock != chain.block.Hash() || heads.Finalized != (common.Hash{}) {
+		t.Fatalf("have head pointers %+v", heads)
+	}
+	var head *types.Header
+	if err := client.CallContext(ctx, &head, "chaindata_headBlockHeader"); err != nil {
+		t.Fatal(err)
+	}
+	if head == nil || head.Hash() != chain.block.Hash() {
+		t.Fatalf("have head block header %v, want block 1", head)
+	}
+}
+
+func TestChainDataBodiesAndReceipts(t *testing.T) {
+	chain, client := newTestChainDataClient(t)
+	ctx := context.Background()
+
+	for _, call := range []struct {
+		suffix string
+		arg    interface{}
+	}{{"ByNumber", uint64(1)}, {"ByHash", chain.block.Hash()}} {
+		var body *Body
+		if err := client.CallContext(ctx, &body, "chaindata_body"+call.suffix, call.arg); err != nil {
+			t.Fatal(err)
+		}
+		if body == nil || len(body.Transactions) != 1 || body.Transactions[0].Hash() != chain.block.Transactions()[0].Hash() {
+			t.Fatalf("body%s: have %+v, want the transaction of block 1", call.suffix, body)
+		}
+
+		var receipts types.Receipts
+		if err := client.CallContext(ctx, &receipts, "chaindata_receipts"+call.suffix, call.arg); err != nil {
+			t.Fatal(err)
+		}
+		if len(receipts) != 1 {
+			t.Fatalf("receipts%s: have %d receipts, want 1", call.suffix, len(receipts))
+		}
+		// the metadata fields are derived from the block, as the chain config is present
+		r := receipts[0]
+		if r.Status != types.ReceiptStatusSuccessful || r.CumulativeGasUsed != 21000 || r.TxHash != chain.block.Transactions()[0].Hash() ||
+			r.BlockHash != chain.block.Hash() || r.BlockNumber.Uint64() != 1 {
+			t.Fatalf("receipts%s: have %+v", call.suffix, r)
+		}
+	}
+}
+
+// TestChainDataMissing checks that data that isn't in the database is returned as null rather than an error
+func TestChainDataMissing(t *testing.T) {
+	_, client := newTestChainDataClient(t)
+	ctx := context.Background()
+	missing := common.HexToHash("0xdead")
+
+	for _, call := range []struct {
+		method string
+		arg    interface{}
+	}{
+		{"chaindata_canonicalHash", uint64(2)},
+		{"chaindata_headerNumber", missing},
+		{"chaindata_headerByNumber", uint64(2)},
+		{"chaindata_headerByHash", missing},
+		{"chaindata_bodyByNumber", uint64(2)},
+		{"chaindata_bodyByHash", missing},
+		{"chaindata_receiptsByNumber", uint64(2)},
+		{"chaindata_receiptsByHash", missing},
+		{"chaindata_totalDifficulty", missing},
+	} {
+		var result interface{}
+		if err := client.CallContext(ctx, &result, call.method, call.arg); err != nil {
+			t.Fatalf("%s: %v", call.method, err)
+		}
+		if result != nil {
+			t.Fatalf("%s(%v): have %v, want null", call.method, call.arg, result)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+var errOutOfBounds = errors.New("out of bounds")
+
+var _ ethdb.AncientReaderOp = &ancientView{}
+
+// ReadAncientsBatch executes a list of freezer reads against a single consistent view of the freezer on the server
+// The error of an individual operation is reported in its result rather than failing the whole batch
+func (d *DatabaseClient) ReadAncientsBatch(ops []leveldb_ethdb_rpc.AncientOp) ([]leveldb_ethdb_rpc.AncientOpResult, error) {
+	return d.ReadAncientsBatchContext(d.ctx, ops)
+}
+
+// ReadAncientsBatchContext is ReadAncientsBatch with a context, which cancels the request once done
+func (d *DatabaseClient) ReadAncientsBatchContext(ctx context.Context, ops []leveldb_ethdb_rpc.AncientOp) ([]leveldb_ethdb_rpc.AncientOpResult, error) {
+	var resp []leveldb_ethdb_rpc.AncientOpResult
+	err := d.call(ctx, &resp, "leveldb_readAncients", ops)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) != len(ops) {
+		return nil, fmt.Errorf("expected %d results, got %d", len(ops), len(resp))
+	}
+
+	return resp, nil
+}
+
+// ancientView is an ethdb.AncientReaderOp pinned to the freezer bounds read at the start of ReadAncients
+// As the freezer is append-only, items within the bounds don't change while the view is in use,
+// and items appended after the view was taken are hidden from it
+type ancientView struct {
+	d        *DatabaseClient
+	ancients uint64
+	tail     uint64
+}
+
+func (v *ancientView) inBounds(number uint64) bool {
+	return number >= v.tail && number < v.ancients
+}
+
+func (v *ancientView) HasAncient(kind string, number uint64) (bool, error) {
+	if !v.inBounds(number) {
+		return false, nil
+	}
+	return v.d.HasAncient(kind, number)
+}
+
+func (v *ancientView) Ancient(kind string, number uint64) ([]byte, error) {
+	if !v.inBounds(number) {
+		return nil, errOutOfBounds
+	}
+	return v.d.Ancient(kind, number)
+}
+
+func (v *ancientView) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if !v.inBounds(start) {
+		return nil, errOutOfBounds
+	}
+	if start+count > v.ancients {
+		count = v.ancients - start
+	}
+	return v.d.AncientRange(kind, start, count, maxBytes)
+}
+
+func (v *ancientView) Ancients() (uint64, error) {
+	return v.ancients, nil
+}
+
+func (v *ancientView) Tail() (uint64, error) {
+	return v.tail, nil
+}
+
+func (v *ancientView) AncientSize(kind string) (uint64, error) {
+	return v.d.AncientSize(kind)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// TestAncientRangeCaps checks that ancientRange cuts ranges exceeding the server's caps short, ancientRangePage
+// reports it, and the client pages through them
+func TestAncientRangeCaps(t *testing.T) {
+	conf := newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)
+	conf.MaxAncientRangeCount = 2
+	url := startTestHTTP(t, newTestServer(t, conf))
+	jsonDB, binaryDB := newTestClient(t, url), newTestClient(t, url, WithBinaryTransport())
+	ctx := context.Background()
+
+	params := &leveldb_ethdb_rpc.BinaryAncientRangeParams{Kind: "bodies", Start: testTail, Count: testAncients}
+	var items [][]byte
+	if err := jsonDB.endpoints[0].client.CallContext(ctx, &items, "leveldb_ancientRange", params.Kind, params.Start, params.Count, 0); err != nil || len(items) != 2 {
+		t.Fatalf("json: have %d items, %v, want 2", len(items), err)
+	}
+	if err := binaryDB.endpoints[0].binary.call(ctx, &items, leveldb_ethdb_rpc.BinaryMethodAncientRange, params); err != nil || len(items) != 2 {
+		t.Fatalf("binary: have %d items, %v, want 2", len(items), err)
+	}
+	var page leveldb_ethdb_rpc.AncientRangeResult
+	if err := jsonDB.endpoints[0].client.CallContext(ctx, &page, "leveldb_ancientRangePage", params.Kind, params.Start, params.Count, 0); err != nil || len(page.Items) != 2 || !page.Truncated {
+		t.Fatalf("json page: have %d items, truncated %v, %v, want 2 and truncated", len(page.Items), page.Truncated, err)
+	}
+	// a range ending at the end of the freezer isn't truncated
+	if err := jsonDB.endpoints[0].client.CallContext(ctx, &page, "leveldb_ancientRangePage", params.Kind, testAncients-2, params.Count, 0); err != nil || len(page.Items) != 2 || page.Truncated {
+		t.Fatalf("json page: have %d items, truncated %v, %v, want 2 and not truncated", len(page.Items), page.Truncated, err)
+	}
+
+	for _, db := range []*DatabaseClient{jsonDB, binaryDB} {
+		items, err := db.AncientRange("bodies", testTail, testAncients, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != testAncients-testTail {
+			t.Fatalf("have %d items, want %d", len(items), testAncients-testTail)
+		}
+		for i, item := range items {
+			if want := testAncient("bodies", testTail+uint64(i)); string(item) != string(want) {
+				t.Fatalf("item %d: have %q, want %q", i, item, want)
+			}
+		}
+	}
+}
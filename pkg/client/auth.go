@@ -0,0 +1,60 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ReadJWTSecret reads the hex encoded 32 byte JWT secret shared with the server from the given file
+func ReadJWTSecret(path string) ([32]byte, error) {
+	var secret [32]byte
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return secret, err
+	}
+	raw := common.FromHex(strings.TrimSpace(string(data)))
+	if len(raw) != len(secret) {
+		return secret, fmt.Errorf("invalid JWT secret in %s: expected %d bytes, got %d", path, len(secret), len(raw))
+	}
+	copy(secret[:], raw)
+	return secret, nil
+}
+
+// newJWTAuth returns an rpc.HTTPAuth signing every request with a fresh JWT carrying the given subject,
+// which the server identifies the client by for rate limiting
+func newJWTAuth(secret [32]byte, subject string) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			Subject:  subject,
+		})
+		signed, err := token.SignedString(secret[:])
+		if err != nil {
+			return fmt.Errorf("failed to create JWT token: %w", err)
+		}
+		h.Set("Authorization", "Bearer "+signed)
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+var _ ethdb.Batch = &batch{}
+
+// batch satisfies the ethdb.Batch interface by buffering writes locally
+// and shipping them to the server as a single atomic leveldb_writeBatch request when Write is called
+// A batch cannot be used concurrently.
+type batch struct {
+	client *DatabaseClient
+	ops    []leveldb_ethdb_rpc.BatchOp
+	size   int
+}
+
+// Put satisfies the ethdb.KeyValueWriter interface
+// Put inserts the given value into the batch for later committing
+func (b *batch) Put(key []byte, value []byte) error {
+	b.ops = append(b.ops, leveldb_ethdb_rpc.BatchOp{Key: common.CopyBytes(key), Value: common.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete satisfies the ethdb.KeyValueWriter interface
+// Delete inserts the key removal into the batch for later committing
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, leveldb_ethdb_rpc.BatchOp{Key: common.CopyBytes(key), Delete: true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize satisfies the ethdb.Batch interface
+// ValueSize retrieves the amount of data queued up for writing
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write satisfies the ethdb.Batch interface
+// Write sends the accumulated writes to the server, which applies them atomically
+func (b *batch) Write() error {
+	err := b.client.call(b.client.ctx, nil, "leveldb_writeBatch", b.ops)
+	if err != nil {
+		return err
+	}
+	if b.client.cache != nil {
+		for _, op := range b.ops {
+			b.client.cache.remove(keyValueCacheKey(op.Key))
+		}
+	}
+
+	return nil
+}
+
+// Reset satisfies the ethdb.Batch interface
+// Reset resets the batch for reuse
+func (b *batch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay satisfies the ethdb.Batch interface
+// Replay replays the batch contents
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	var err error
+	for _, op := range b.ops {
+		if op.Delete {
+			err = w.Delete(op.Key)
+		} else {
+			err = w.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
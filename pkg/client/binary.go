@@ -0,0 +1,105 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// binaryTransport sends requests to the RLP framed binary transport of the server
+type binaryTransport struct {
+	url    string
+	client *http.Client
+	auth   rpc.HTTPAuth
+}
+
+// negotiateBinaryTransport asks the server at rawurl for the path of its binary transport
+// it returns nil, and the client keeps using JSON-RPC, if the server is not reached over HTTP or doesn't offer one;
+// it only fails if the server couldn't be reached
+func negotiateBinaryTransport(ctx context.Context, rpcClient *rpc.Client, rawurl string, client *http.Client, auth rpc.HTTPAuth) (*binaryTransport, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil
+	}
+	var path string
+	if err := rpcClient.CallContext(ctx, &path, "leveldb_binaryTransport"); err != nil {
+		if isTransient(ctx, err) || IsThrottled(err) {
+			return nil, err
+		}
+		log.WithError(err).Debug("server does not offer a binary transport, using JSON-RPC")
+		return nil, nil
+	}
+	endpoint, err := u.Parse(path)
+	if err != nil {
+		log.WithError(err).Warn("server returned an invalid binary transport path, using JSON-RPC")
+		return nil, nil
+	}
+	return &binaryTransport{url: endpoint.String(), client: client, auth: auth}, nil
+}
+
+// call sends a single request and decodes its result into result
+func (t *binaryTransport) call(ctx context.Context, result interface{}, method string, params interface{}) error {
+	encParams, err := rlp.EncodeToBytes(params)
+	if err != nil {
+		return err
+	}
+	body, err := rlp.EncodeToBytes(&leveldb_ethdb_rpc.BinaryRequest{Method: method, Params: encParams})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", leveldb_ethdb_rpc.BinaryContentType)
+	if t.auth != nil {
+		if err := t.auth(req.Header); err != nil {
+			return err
+		}
+	}
+	httpResp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return rpc.HTTPError{Status: httpResp.Status, StatusCode: httpResp.StatusCode, Body: bytes.TrimSpace(respBody)}
+	}
+
+	var resp leveldb_ethdb_rpc.BinaryResponse
+	if err := rlp.DecodeBytes(respBody, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return rlp.DecodeBytes(resp.Result, result)
+}
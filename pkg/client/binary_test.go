@@ -0,0 +1,139 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// newTransportClients returns a client using JSON-RPC and one using the binary transport, connected to the same server
+func newTransportClients(t testing.TB) (jsonDB, binaryDB *DatabaseClient) {
+	t.Helper()
+	url := startTestHTTP(t, newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)))
+	return newTestClient(t, url), newTestClient(t, url, WithBinaryTransport())
+}
+
+func TestBinaryTransport(t *testing.T) {
+	jsonDB, binaryDB := newTransportClients(t)
+	checkTestKeys(t, binaryDB)
+	if binaryDB.endpoints[0].binary == nil {
+		t.Fatal("binary transport not negotiated")
+	}
+	if jsonDB.endpoints[0].binary != nil {
+		t.Fatal("binary transport negotiated without being asked for")
+	}
+
+	if _, err := binaryDB.Get([]byte("missing")); err == nil {
+		t.Fatal("expected an error getting a missing key")
+	}
+
+	keys := [][]byte{testKey(0), []byte("missing"), testKey(testKeys - 1)}
+	jsonValues, jsonFound, err := jsonDB.GetMany(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryValues, binaryFound, err := binaryDB.GetMany(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jsonValues, binaryValues) || !reflect.DeepEqual(jsonFound, binaryFound) {
+		t.Fatalf("get many: json %q %v, binary %q %v", jsonValues, jsonFound, binaryValues, binaryFound)
+	}
+
+	for _, kind := range testTables {
+		jsonItem, err := jsonDB.Ancient(kind, testTail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryItem, err := binaryDB.Ancient(kind, testTail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(jsonItem) != string(binaryItem) {
+			t.Fatalf("ancient %s: json %q, binary %q", kind, jsonItem, binaryItem)
+		}
+
+		jsonItems, err := jsonDB.AncientRange(kind, testTail, testAncients, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryItems, err := binaryDB.AncientRange(kind, testTail, testAncients, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(binaryItems) != testAncients-testTail || !reflect.DeepEqual(jsonItems, binaryItems) {
+			t.Fatalf("ancient range %s: json %q, binary %q", kind, jsonItems, binaryItems)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	jsonDB, binaryDB := newTransportClients(b)
+	for _, bench := range []struct {
+		name string
+		db   *DatabaseClient
+	}{{"json", jsonDB}, {"binary", binaryDB}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bench.db.Get(testKey(i % testKeys)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	jsonDB, binaryDB := newTransportClients(b)
+	keys := make([][]byte, leveldb_ethdb_rpc.MaxManyKeys)
+	for i := range keys {
+		keys[i] = testKey(i % testKeys)
+	}
+	for _, bench := range []struct {
+		name string
+		db   *DatabaseClient
+	}{{"json", jsonDB}, {"binary", binaryDB}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := bench.db.GetMany(keys); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAncientRange(b *testing.B) {
+	jsonDB, binaryDB := newTransportClients(b)
+	for _, bench := range []struct {
+		name string
+		db   *DatabaseClient
+	}{{"json", jsonDB}, {"binary", binaryDB}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bench.db.AncientRange("bodies", testTail, testAncients, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// number (uint64 big endian) + hash
+const numHashLength = 8 + common.HashLength
+
+var (
+	// these mirror the unexported key prefixes of the rawdb schema
+	headerPrefix        = []byte("h") // headerPrefix + num + hash -> header
+	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num + hash -> block body
+	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num + hash -> block receipts
+)
+
+// CacheStats holds the hit and miss counters of the client cache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// cache is a bounded read-through cache for immutable database entries, whose capacity is in bytes
+// this mirrors lru.SizeConstrainedCache, with the addition of remove for entries deleted through the client
+type cache struct {
+	lock    sync.Mutex
+	entries lru.BasicLRU[string, []byte]
+	size    uint64
+	maxSize uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func newCache(maxSize uint64) *cache {
+	return &cache{
+		entries: lru.NewBasicLRU[string, []byte](math.MaxInt),
+		maxSize: maxSize,
+	}
+}
+
+func (c *cache) get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	value, ok := c.entries.Get(key)
+	c.lock.Unlock()
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// add adds a value to the cache, evicting the least recently used entries until it fits
+func (c *cache) add(key string, value []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.entries.Contains(key) {
+		targetSize := c.size + uint64(len(value))
+		for targetSize > c.maxSize {
+			_, v, ok := c.entries.RemoveOldest()
+			if !ok {
+				break
+			}
+			targetSize -= uint64(len(v))
+		}
+		c.size = targetSize
+	}
+	c.entries.Add(key, value)
+}
+
+func (c *cache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if value, ok := c.entries.Peek(key); ok {
+		c.entries.Remove(key)
+		c.size -= uint64(len(value))
+	}
+}
+
+func (c *cache) stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// cacheableKey reports whether the value stored under the key may be immutable, i.e. the key is derived from
+// the hash of its content: legacy trie nodes, contract code, preimages and headers/bodies/receipts by number and hash
+// mutable entries such as head pointers, canonical hash mappings and path-based trie nodes are never cached
+// 32 byte keys are shared by legacy trie nodes and path-based account trie nodes, see cacheableValue
+func cacheableKey(key []byte) bool {
+	if len(key) == common.HashLength {
+		return true
+	}
+	if ok, _ := rawdb.IsCodeKey(key); ok {
+		return true
+	}
+	if bytes.HasPrefix(key, rawdb.PreimagePrefix) && len(key) == len(rawdb.PreimagePrefix)+common.HashLength {
+		return true
+	}
+	if len(key) == 1+numHashLength {
+		switch {
+		case bytes.HasPrefix(key, headerPrefix), bytes.HasPrefix(key, blockBodyPrefix), bytes.HasPrefix(key, blockReceiptsPrefix):
+			return true
+		}
+	}
+	return false
+}
+
+// cacheableValue reports whether the value read under a cacheable key can be cached
+// a 32 byte key only holds an immutable legacy trie node if it is the hash of the value, as in geth's rawdb.ReadTrieNode
+func cacheableValue(key, value []byte) bool {
+	if len(key) == common.HashLength {
+		return rawdb.IsLegacyTrieNode(key, value)
+	}
+	return true
+}
+
+// Cache keys are tagged with the store they come from so that key-value entries and freezer items can't collide
+const (
+	keyValueCacheTag = 'k'
+	ancientCacheTag  = 'a'
+)
+
+// keyValueCacheKey returns the cache key for an entry of the key-value store
+func keyValueCacheKey(key []byte) string {
+	return string(keyValueCacheTag) + string(key)
+}
+
+// ancientCacheKey returns the cache key for an item of the freezer
+func ancientCacheKey(kind string, number uint64) string {
+	key := make([]byte, 0, 1+8+len(kind))
+	key = append(key, ancientCacheTag)
+	key = binary.BigEndian.AppendUint64(key, number)
+	key = append(key, kind...)
+	return string(key)
+}
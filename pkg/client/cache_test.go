@@ -0,0 +1,43 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCacheableValue(t *testing.T) {
+	node := []byte("legacy trie node")
+	hash := crypto.Keccak256(node)
+	if !cacheableKey(hash) || !cacheableValue(hash, node) {
+		t.Fatal("hash-keyed trie node not cacheable")
+	}
+
+	// a path-based account trie node: "A" followed by a 31 byte path
+	path := append([]byte("A"), make([]byte, 31)...)
+	if !cacheableKey(path) {
+		t.Fatal("32 byte key not a cache candidate")
+	}
+	if cacheableValue(path, node) {
+		t.Fatal("path-based trie node cacheable")
+	}
+
+	if cacheableKey([]byte("LastBlock")) {
+		t.Fatal("head pointer cacheable")
+	}
+}
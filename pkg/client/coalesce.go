@@ -0,0 +1,129 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// getResult is the outcome of a single coalesced Get
+type getResult struct {
+	value []byte
+	err   error
+}
+
+// getRequest is a Get waiting to be sent as part of the next leveldb_getMany request
+type getRequest struct {
+	ctx  context.Context
+	key  []byte
+	resp chan getResult
+}
+
+// coalescer groups concurrent Get calls into batched leveldb_getMany requests
+type coalescer struct {
+	client *DatabaseClient
+
+	mu       sync.Mutex
+	pending  []*getRequest
+	flushing bool
+}
+
+func newCoalescer(client *DatabaseClient) *coalescer {
+	return &coalescer{client: client}
+}
+
+// get queues the key for the next batch and waits for its result, or until ctx is done
+func (c *coalescer) get(ctx context.Context, key []byte) ([]byte, error) {
+	req := &getRequest{ctx: ctx, key: key, resp: make(chan getResult, 1)}
+	c.mu.Lock()
+	c.pending = append(c.pending, req)
+	if !c.flushing {
+		c.flushing = true
+		go c.flush()
+	}
+	c.mu.Unlock()
+
+	select {
+	case res := <-req.resp:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush sends the pending requests in batches until there are none left
+func (c *coalescer) flush() {
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.flushing = false
+			c.mu.Unlock()
+			return
+		}
+		batch := c.pending
+		if len(batch) > leveldb_ethdb_rpc.MaxManyKeys {
+			batch = batch[:leveldb_ethdb_rpc.MaxManyKeys]
+		}
+		c.pending = c.pending[len(batch):]
+		c.mu.Unlock()
+
+		c.send(batch)
+	}
+}
+
+// send requests the keys of the batch, skipping those whose callers have already given up
+// the request is cancelled once every caller waiting on it has given up, rather than by any one of them
+func (c *coalescer) send(batch []*getRequest) {
+	live := make([]*getRequest, 0, len(batch))
+	for _, req := range batch {
+		if req.ctx.Err() == nil {
+			live = append(live, req)
+		}
+	}
+	if len(live) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(live[0].ctx))
+	defer cancel()
+	var waiting atomic.Int32
+	waiting.Store(int32(len(live)))
+	keys := make([][]byte, len(live))
+	for i, req := range live {
+		keys[i] = req.key
+		stop := context.AfterFunc(req.ctx, func() {
+			if waiting.Add(-1) == 0 {
+				cancel()
+			}
+		})
+		defer stop()
+	}
+
+	values, found, err := c.client.GetManyContext(ctx, keys)
+	for i, req := range live {
+		switch {
+		case err != nil:
+			req.resp <- getResult{err: err}
+		case !found[i]:
+			req.resp <- getResult{err: errNotFound}
+		default:
+			req.resp <- getResult{value: values[i]}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// holdGetMany counts the leveldb_getMany requests, and runs hold before serving each of them
+// with the number of the request and its context; the request isn't served if hold returns false
+func holdGetMany(calls *atomic.Int32, hold func(n int32, ctx context.Context) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(`"leveldb_getMany"`)) {
+				if n := calls.Add(1); hold != nil && !hold(n, r.Context()) {
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// pendingGets returns the number of Gets waiting for the next batch of the coalescer
+func pendingGets(c *coalescer) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+func TestGetManyHasMany(t *testing.T) {
+	var calls atomic.Int32
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	db := newTestClient(t, startTestHTTPWith(t, srv, nil, holdGetMany(&calls, nil)))
+
+	// more keys than fit in a request, every third of them missing
+	keys := make([][]byte, 2*leveldb_ethdb_rpc.MaxManyKeys+1)
+	for i := range keys {
+		if i%3 == 2 {
+			keys[i] = []byte("missing")
+		} else {
+			keys[i] = testKey(i)
+		}
+	}
+	values, found, err := db.GetMany(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != len(keys) || len(found) != len(keys) {
+		t.Fatalf("have %d values and %d found markers for %d keys", len(values), len(found), len(keys))
+	}
+	for i := range keys {
+		if present := i%3 != 2; found[i] != present || (present && !bytes.Equal(values[i], testValue(i))) {
+			t.Fatalf("key %s: have %q (found %v)", keys[i], values[i], found[i])
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("have %d getMany requests, want the keys split into 3", n)
+	}
+
+	has, err := db.HasMany(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range keys {
+		if has[i] != (i%3 != 2) {
+			t.Fatalf("key %s: have found %v", keys[i], has[i])
+		}
+	}
+}
+
+func TestCoalescedGets(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	url := startTestHTTPWith(t, srv, nil, holdGetMany(&calls, func(n int32, ctx context.Context) bool {
+		// hold the first batch, so that the other Gets queue up behind it
+		if n == 1 {
+			<-release
+		}
+		return true
+	}))
+	db := newTestClient(t, url, WithGetCoalescing())
+
+	const gets = 100
+	errs := make([]error, gets)
+	values := make([][]byte, gets)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		values[0], errs[0] = db.Get(testKey(0))
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < gets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := testKey(i)
+			if i%10 == 0 {
+				key = []byte("missing")
+			}
+			values[i], errs[i] = db.Get(key)
+		}(i)
+	}
+	for pendingGets(db.coalescer) != gets-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("have %d getMany requests, want 2", n)
+	}
+	for i := 0; i < gets; i++ {
+		if i != 0 && i%10 == 0 {
+			if !errors.Is(errs[i], errNotFound) {
+				t.Fatalf("get %d: have %q, %v, want not found", i, values[i], errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil || !bytes.Equal(values[i], testValue(i)) {
+			t.Fatalf("get %d: have %q, %v, want %q", i, values[i], errs[i], testValue(i))
+		}
+	}
+}
+
+// TestCoalescedGetContext checks that a batch is sent with the contexts of its callers: it carries on while any
+// of them is waiting, and is cancelled once all of them have given up
+func TestCoalescedGetContext(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	cancelled := make(chan int32, 4)
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	url := startTestHTTPWith(t, srv, nil, holdGetMany(&calls, func(n int32, ctx context.Context) bool {
+		if n == 1 {
+			<-release
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			cancelled <- n
+			return false
+		case <-time.After(200 * time.Millisecond):
+			return true
+		}
+	}))
+	db := newTestClient(t, url, WithGetCoalescing(), WithRetry(0, 0))
+
+	// the first Get holds the coalescer, so that the next two are sent together
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		db.Get(testKey(0))
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var (
+		shortErr, longErr error
+		longValue         []byte
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, shortErr = db.GetContext(ctx, testKey(1))
+	}()
+	go func() {
+		defer wg.Done()
+		longValue, longErr = db.GetContext(context.Background(), testKey(2))
+	}()
+	for pendingGets(db.coalescer) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if !errors.Is(shortErr, context.DeadlineExceeded) {
+		t.Fatalf("have error %v for the caller that timed out, want its deadline exceeded", shortErr)
+	}
+	if longErr != nil || !bytes.Equal(longValue, testValue(2)) {
+		t.Fatalf("have %q, %v for the caller still waiting, want %q", longValue, longErr, testValue(2))
+	}
+	select {
+	case n := <-cancelled:
+		t.Fatalf("batch %d cancelled while a caller was waiting on it", n)
+	default:
+	}
+
+	// a batch whose only caller gives up is cancelled rather than left to run
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.GetContext(ctx, testKey(3)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("have error %v, want the deadline exceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request of the abandoned batch wasn't cancelled")
+	}
+}
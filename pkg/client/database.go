@@ -16,40 +16,162 @@
 package client
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"time"
 
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
 )
 
-var errNotSupported = errors.New("this operation is not supported")
+var (
+	errNotSupported = errors.New("this operation is not supported")
+	errNotFound     = errors.New("not found")
+)
 
 var _ ethdb.Database = &DatabaseClient{}
 
 // Type that satisfies the ethdb.DatabaseClient using a leveldb-ethdb-rpc client
 type DatabaseClient struct {
-	client *rpc.Client
+	// endpoints are the server and its replicas, in order of preference
+	endpoints []*endpoint
+	dialer    *dialer
+	coalescer *coalescer
+	cache     *cache
+	// ctx is the context of the requests sent by the methods that don't take one
+	ctx context.Context
+
+	// timeout bounds every request; transient errors are retried retries times, after a delay starting at retryDelay
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+	// throttled requests are retried throttleRetries times, after a delay starting at throttleDelay
+	throttleRetries int
+	throttleDelay   time.Duration
 }
 
 // NewDatabase returns a ethdb.Database interface
-func NewDatabaseClient(url string) (ethdb.Database, error) {
-	rpcClient, err := rpc.Dial(url)
-	if err != nil {
-		return nil, err
+func NewDatabaseClient(url string, opts ...Option) (ethdb.Database, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
 
+	urls := append([]string{url}, o.replicas...)
+	if o.database != "" {
+		for i := range urls {
+			var err error
+			if urls[i], err = databaseURL(urls[i], o.database); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var (
+		auth       rpc.HTTPAuth
+		dialOpts   []rpc.ClientOption
+		httpClient = http.DefaultClient
+	)
+	if o.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = o.tlsConfig
+		httpClient = &http.Client{Transport: transport}
+		dialOpts = append(dialOpts, rpc.WithHTTPClient(httpClient))
+	}
+	if o.jwtSecret != nil {
+		auth = node.NewJWTAuth(*o.jwtSecret)
+		if o.jwtSubject != "" {
+			auth = newJWTAuth(*o.jwtSecret, o.jwtSubject)
+		}
+		dialOpts = append(dialOpts, rpc.WithHTTPAuth(auth))
+	}
 	database := DatabaseClient{
-		client: rpcClient,
+		ctx:             context.Background(),
+		dialer:          &dialer{opts: dialOpts, httpClient: httpClient, auth: auth, binary: o.binary},
+		timeout:         o.timeout,
+		retries:         o.retries,
+		retryDelay:      o.retryDelay,
+		throttleRetries: o.throttleRetries,
+		throttleDelay:   o.throttleDelay,
+	}
+	for _, url := range urls {
+		database.endpoints = append(database.endpoints, &endpoint{url: url})
+	}
+	if o.coalesceGets {
+		database.coalescer = newCoalescer(&database)
+	}
+	if o.cacheSize > 0 {
+		database.cache = newCache(o.cacheSize)
+	}
+
+	// connect to the first endpoint right away, so that a single unreachable server is reported here;
+	// with replicas, the others are tried when the first request is sent. A throttled connection is left to
+	// the first request, which backs off as configured
+	ctx := context.Background()
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := database.endpoints[0].connect(ctx, database.dialer); err != nil && !IsThrottled(err) {
+		if len(database.endpoints) == 1 {
+			return nil, err
+		}
+		log.WithError(err).Warnf("failed to connect to %s", database.endpoints[0].url)
+		database.endpoints[0].markDown()
 	}
 
 	return &database, nil
 }
 
+// WithContext returns a copy of the client whose methods that don't take a context, including those of
+// the ethdb interfaces and of the iterators, snapshots and batches it creates, send their requests with ctx,
+// so that a deadline or cancellation can be applied to code that only takes an ethdb.Database;
+// the copy shares its connections, cache and settings with the client
+func (d *DatabaseClient) WithContext(ctx context.Context) *DatabaseClient {
+	c := *d
+	c.ctx = ctx
+	return &c
+}
+
+// databaseURL returns the URL the named database is served on by the server at rawurl
+func databaseURL(rawurl string, name string) (string, error) {
+	u, err := neturl.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		return "", fmt.Errorf("selecting database %s requires an HTTP or websocket endpoint", name)
+	}
+	u.Path = path.Join("/", u.Path, name)
+	return u.String(), nil
+}
+
 // Has satisfies the ethdb.KeyValueReader interface
 // Has retrieves if a key is present in the key-value data store
 func (d *DatabaseClient) Has(key []byte) (bool, error) {
+	return d.HasContext(d.ctx, key)
+}
+
+// HasContext is Has with a context, which cancels the request once done
+func (d *DatabaseClient) HasContext(ctx context.Context, key []byte) (bool, error) {
+	if d.cache != nil && cacheableKey(key) {
+		if _, ok := d.cache.get(keyValueCacheKey(key)); ok {
+			return true, nil
+		}
+	}
 	var resp bool
-	err := d.client.Call(&resp, "leveldb_has", key)
+	err := d.call(ctx, &resp, "leveldb_has", key)
 	if err != nil {
 		return resp, err
 	}
@@ -60,8 +182,39 @@ func (d *DatabaseClient) Has(key []byte) (bool, error) {
 // Get satisfies the ethdb.KeyValueReader interface
 // Get retrieves the given key if it's present in the key-value data store
 func (d *DatabaseClient) Get(key []byte) ([]byte, error) {
+	return d.GetContext(d.ctx, key)
+}
+
+// GetContext is Get with a context, which cancels the request once done
+func (d *DatabaseClient) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	cacheable := d.cache != nil && cacheableKey(key)
+	if cacheable {
+		if value, ok := d.cache.get(keyValueCacheKey(key)); ok {
+			return value, nil
+		}
+	}
+	resp, err := d.get(ctx, key)
+	if err != nil {
+		return resp, err
+	}
+	if cacheable && cacheableValue(key, resp) {
+		d.cache.add(keyValueCacheKey(key), resp)
+	}
+
+	return resp, nil
+}
+
+func (d *DatabaseClient) get(ctx context.Context, key []byte) ([]byte, error) {
+	if d.coalescer != nil {
+		return d.coalescer.get(ctx, key)
+	}
 	var resp []byte
-	err := d.client.Call(&resp, "leveldb_get", key)
+	err := d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		if ep.binary != nil {
+			return ep.binary.call(ctx, &resp, leveldb_ethdb_rpc.BinaryMethodGet, &leveldb_ethdb_rpc.BinaryGetParams{Key: key})
+		}
+		return ep.client.CallContext(ctx, &resp, "leveldb_get", key)
+	})
 	if err != nil {
 		return resp, err
 	}
@@ -69,24 +222,130 @@ func (d *DatabaseClient) Get(key []byte) ([]byte, error) {
 	return resp, nil
 }
 
+// CacheStats returns the hit and miss counters of the client cache, which are zero if it is disabled
+func (d *DatabaseClient) CacheStats() CacheStats {
+	if d.cache == nil {
+		return CacheStats{}
+	}
+	return d.cache.stats()
+}
+
+// GetMany retrieves the values for a list of keys, batching them into as few requests as possible
+// found[i] reports whether the i-th key is present; values[i] is nil if it is not
+func (d *DatabaseClient) GetMany(keys [][]byte) (values [][]byte, found []bool, err error) {
+	return d.GetManyContext(d.ctx, keys)
+}
+
+// GetManyContext is GetMany with a context, which cancels the request once done
+func (d *DatabaseClient) GetManyContext(ctx context.Context, keys [][]byte) (values [][]byte, found []bool, err error) {
+	values = make([][]byte, 0, len(keys))
+	found = make([]bool, 0, len(keys))
+	for start := 0; start < len(keys); start += leveldb_ethdb_rpc.MaxManyKeys {
+		chunk := keys[start:min(start+leveldb_ethdb_rpc.MaxManyKeys, len(keys))]
+		var resp leveldb_ethdb_rpc.GetManyResult
+		err = d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+			if ep.binary == nil {
+				return ep.client.CallContext(ctx, &resp, "leveldb_getMany", chunk)
+			}
+			var binResp leveldb_ethdb_rpc.BinaryGetManyResult
+			if err := ep.binary.call(ctx, &binResp, leveldb_ethdb_rpc.BinaryMethodGetMany, &leveldb_ethdb_rpc.BinaryGetManyParams{Keys: chunk}); err != nil {
+				return err
+			}
+			resp.Values, resp.Found = binResp.Values, binResp.Found
+			for i := range resp.Found {
+				if !resp.Found[i] {
+					resp.Values[i] = nil
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(resp.Values) != len(chunk) || len(resp.Found) != len(chunk) {
+			return nil, nil, fmt.Errorf("expected %d results, got %d", len(chunk), len(resp.Values))
+		}
+		values = append(values, resp.Values...)
+		found = append(found, resp.Found...)
+	}
+
+	return values, found, nil
+}
+
+// HasMany reports whether each key in a list is present, batching them into as few requests as possible
+func (d *DatabaseClient) HasMany(keys [][]byte) ([]bool, error) {
+	return d.HasManyContext(d.ctx, keys)
+}
+
+// HasManyContext is HasMany with a context, which cancels the request once done
+func (d *DatabaseClient) HasManyContext(ctx context.Context, keys [][]byte) ([]bool, error) {
+	found := make([]bool, 0, len(keys))
+	for start := 0; start < len(keys); start += leveldb_ethdb_rpc.MaxManyKeys {
+		chunk := keys[start:min(start+leveldb_ethdb_rpc.MaxManyKeys, len(keys))]
+		var resp []bool
+		err := d.call(ctx, &resp, "leveldb_hasMany", chunk)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) != len(chunk) {
+			return nil, fmt.Errorf("expected %d results, got %d", len(chunk), len(resp))
+		}
+		found = append(found, resp...)
+	}
+
+	return found, nil
+}
+
 // Put satisfies the ethdb.KeyValueWriter interface
 // Put inserts the given value into the key-value data store
-// Key is expected to be the keccak256 hash of value
+// The server must have writes enabled
 func (d *DatabaseClient) Put(key []byte, value []byte) error {
-	return errNotSupported
+	return d.PutContext(d.ctx, key, value)
+}
+
+// PutContext is Put with a context, which cancels the request once done
+func (d *DatabaseClient) PutContext(ctx context.Context, key []byte, value []byte) error {
+	err := d.call(ctx, nil, "leveldb_put", key, value)
+	if err != nil {
+		return err
+	}
+	if d.cache != nil {
+		d.cache.remove(keyValueCacheKey(key))
+	}
+
+	return nil
 }
 
 // Delete satisfies the ethdb.KeyValueWriter interface
 // Delete removes the key from the key-value data store
+// The server must have writes enabled
 func (d *DatabaseClient) Delete(key []byte) error {
-	return errNotSupported
+	return d.DeleteContext(d.ctx, key)
+}
+
+// DeleteContext is Delete with a context, which cancels the request once done
+func (d *DatabaseClient) DeleteContext(ctx context.Context, key []byte) error {
+	err := d.call(ctx, nil, "leveldb_delete", key)
+	if err != nil {
+		return err
+	}
+	if d.cache != nil {
+		d.cache.remove(keyValueCacheKey(key))
+	}
+
+	return nil
 }
 
 // Stat satisfies the ethdb.Stater interface
 // Stat returns a particular internal stat of the database
 func (d *DatabaseClient) Stat(property string) (string, error) {
+	return d.StatContext(d.ctx, property)
+}
+
+// StatContext is Stat with a context, which cancels the request once done
+func (d *DatabaseClient) StatContext(ctx context.Context, property string) (string, error) {
 	var resp string
-	err := d.client.Call(&resp, "leveldb_stat", property)
+	err := d.call(ctx, &resp, "leveldb_stat", property)
 	if err != nil {
 		return resp, err
 	}
@@ -103,14 +362,15 @@ func (d *DatabaseClient) Compact(start []byte, limit []byte) error {
 // NewBatch satisfies the ethdb.Batcher interface
 // NewBatch creates a write-only database that buffers changes to its host db
 // until a final write is called
+// The batch is sent to the server, which must have writes enabled, as a single request when Write is called
 func (d *DatabaseClient) NewBatch() ethdb.Batch {
-	return nil
+	return &batch{client: d}
 }
 
 // NewBatchWithSize satisfies the ethdb.Batcher interface.
 // NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
 func (d *DatabaseClient) NewBatchWithSize(size int) ethdb.Batch {
-	return nil
+	return &batch{client: d}
 }
 
 // NewIterator satisfies the ethdb.Iteratee interface
@@ -121,20 +381,26 @@ func (d *DatabaseClient) NewBatchWithSize(size int) ethdb.Batch {
 // Note: This method assumes that the prefix is NOT part of the start, so there's
 // no need for the caller to prepend the prefix to the start
 func (d *DatabaseClient) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	return nil
+	return newIterator(d, prefix, start)
 }
 
 // Close satisfies the io.Closer interface
 // Close closes the db connection
 func (d *DatabaseClient) Close() error {
-	return errNotSupported
+	d.close()
+	return nil
 }
 
 // HasAncient satisfies the ethdb.AncientReader interface
 // HasAncient returns an indicator whether the specified data exists in the ancient store
 func (d *DatabaseClient) HasAncient(kind string, number uint64) (bool, error) {
+	return d.HasAncientContext(d.ctx, kind, number)
+}
+
+// HasAncientContext is HasAncient with a context, which cancels the request once done
+func (d *DatabaseClient) HasAncientContext(ctx context.Context, kind string, number uint64) (bool, error) {
 	var resp bool
-	err := d.client.Call(&resp, "leveldb_hasAncient", kind, number)
+	err := d.call(ctx, &resp, "leveldb_hasAncient", kind, number)
 	if err != nil {
 		return resp, err
 	}
@@ -145,11 +411,29 @@ func (d *DatabaseClient) HasAncient(kind string, number uint64) (bool, error) {
 // Ancient satisfies the ethdb.AncientReader interface
 // Ancient retrieves an ancient binary blob from the append-only immutable files
 func (d *DatabaseClient) Ancient(kind string, number uint64) ([]byte, error) {
+	return d.AncientContext(d.ctx, kind, number)
+}
+
+// AncientContext is Ancient with a context, which cancels the request once done
+func (d *DatabaseClient) AncientContext(ctx context.Context, kind string, number uint64) ([]byte, error) {
+	if d.cache != nil {
+		if value, ok := d.cache.get(ancientCacheKey(kind, number)); ok {
+			return value, nil
+		}
+	}
 	var resp []byte
-	err := d.client.Call(&resp, "leveldb_ancient", kind, number)
+	err := d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		if ep.binary != nil {
+			return ep.binary.call(ctx, &resp, leveldb_ethdb_rpc.BinaryMethodAncient, &leveldb_ethdb_rpc.BinaryAncientParams{Kind: kind, Number: number})
+		}
+		return ep.client.CallContext(ctx, &resp, "leveldb_ancient", kind, number)
+	})
 	if err != nil {
 		return resp, err
 	}
+	if d.cache != nil {
+		d.cache.add(ancientCacheKey(kind, number), resp)
+	}
 
 	return resp, nil
 }
@@ -157,8 +441,13 @@ func (d *DatabaseClient) Ancient(kind string, number uint64) ([]byte, error) {
 // Ancients satisfies the ethdb.AncientReader interface
 // Ancients returns the ancient item numbers in the ancient store
 func (d *DatabaseClient) Ancients() (uint64, error) {
+	return d.AncientsContext(d.ctx)
+}
+
+// AncientsContext is Ancients with a context, which cancels the request once done
+func (d *DatabaseClient) AncientsContext(ctx context.Context) (uint64, error) {
 	var resp uint64
-	err := d.client.Call(&resp, "leveldb_ancients")
+	err := d.call(ctx, &resp, "leveldb_ancients")
 	if err != nil {
 		return resp, err
 	}
@@ -169,14 +458,30 @@ func (d *DatabaseClient) Ancients() (uint64, error) {
 // Tail satisfies the ethdb.AncientReader interface.
 // Tail returns the number of first stored item in the freezer.
 func (d *DatabaseClient) Tail() (uint64, error) {
-	return 0, errNotSupported
+	return d.TailContext(d.ctx)
+}
+
+// TailContext is Tail with a context, which cancels the request once done
+func (d *DatabaseClient) TailContext(ctx context.Context) (uint64, error) {
+	var resp uint64
+	err := d.call(ctx, &resp, "leveldb_tail")
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
 }
 
 // AncientSize satisfies the ethdb.AncientReader interface
 // AncientSize returns the ancient size of the specified category
 func (d *DatabaseClient) AncientSize(kind string) (uint64, error) {
+	return d.AncientSizeContext(d.ctx, kind)
+}
+
+// AncientSizeContext is AncientSize with a context, which cancels the request once done
+func (d *DatabaseClient) AncientSizeContext(ctx context.Context, kind string) (uint64, error) {
 	var resp uint64
-	err := d.client.Call(&resp, "leveldb_ancientSize")
+	err := d.call(ctx, &resp, "leveldb_ancientSize", kind)
 	if err != nil {
 		return resp, err
 	}
@@ -189,19 +494,88 @@ func (d *DatabaseClient) AncientSize(kind string) (uint64, error) {
 //   - at most 'count' items,
 //   - at least 1 item (even if exceeding the maxBytes), but will otherwise
 //     return as many items as fit into maxBytes.
+//
+// Ranges cut short by the server's caps are completed with further requests.
 func (d *DatabaseClient) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	var resp [][]byte
-	err := d.client.Call(&resp, "leveldb_ancientRange", kind, start, count, maxBytes)
+	return d.AncientRangeContext(d.ctx, kind, start, count, maxBytes)
+}
+
+// AncientRangeContext is AncientRange with a context, which cancels the request once done
+func (d *DatabaseClient) AncientRangeContext(ctx context.Context, kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for {
+		page, err := d.ancientRangePage(ctx, kind, start+uint64(len(items)), count-uint64(len(items)), remainingBytes(maxBytes, size))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			// the server returns at least one item per page, which only the first page may have exceeding maxBytes
+			if maxBytes > 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+				return items, nil
+			}
+			items = append(items, item)
+			size += uint64(len(item))
+		}
+		if !page.Truncated || len(page.Items) == 0 || uint64(len(items)) >= count || (maxBytes > 0 && size >= maxBytes) {
+			return items, nil
+		}
+	}
+}
+
+// remainingBytes returns what is left of a maxBytes budget after size bytes, 0 meaning no limit
+func remainingBytes(maxBytes, size uint64) uint64 {
+	if maxBytes == 0 {
+		return 0
+	}
+	if size >= maxBytes {
+		return 1
+	}
+	return maxBytes - size
+}
+
+// ancientRangePage requests a single page of a range, and whether the server's caps cut it short
+func (d *DatabaseClient) ancientRangePage(ctx context.Context, kind string, start, count, maxBytes uint64) (*leveldb_ethdb_rpc.AncientRangeResult, error) {
+	var resp leveldb_ethdb_rpc.AncientRangeResult
+	err := d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		if ep.binary == nil {
+			return ep.client.CallContext(ctx, &resp, "leveldb_ancientRangePage", kind, start, count, maxBytes)
+		}
+		var binResp leveldb_ethdb_rpc.BinaryAncientRangeResult
+		params := &leveldb_ethdb_rpc.BinaryAncientRangeParams{Kind: kind, Start: start, Count: count, MaxBytes: maxBytes}
+		if err := ep.binary.call(ctx, &binResp, leveldb_ethdb_rpc.BinaryMethodAncientRangePage, params); err != nil {
+			return err
+		}
+		resp = leveldb_ethdb_rpc.AncientRangeResult{Items: binResp.Items, Truncated: binResp.Truncated}
+		return nil
+	})
 	if err != nil {
-		return resp, err
+		return nil, err
 	}
 
-	return resp, nil
+	return &resp, nil
 }
 
-// ReadAncients applies the provided AncientReader function
+// ReadAncients satisfies the ethdb.AncientReader interface
+// ReadAncients applies the provided AncientReader function to a view of the freezer whose item count and tail
+// are read together at the start of the call, so that the function sees consistent bounds
 func (d *DatabaseClient) ReadAncients(fn func(ethdb.AncientReaderOp) error) (err error) {
-	return fn(d)
+	results, err := d.ReadAncientsBatchContext(d.ctx, []leveldb_ethdb_rpc.AncientOp{
+		{Op: leveldb_ethdb_rpc.AncientOpAncients},
+		{Op: leveldb_ethdb_rpc.AncientOpTail},
+	})
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			return errors.New(res.Error)
+		}
+	}
+
+	return fn(&ancientView{d: d, ancients: results[0].Number, tail: results[1].Number})
 }
 
 // ModifyAncients satisfies the ethdb.AncientWriter interface.
@@ -224,8 +598,14 @@ func (d *DatabaseClient) TruncateTail(n uint64) (uint64, error) {
 
 // Sync satisfies the ethdb.AncientWriter interface
 // Sync flushes all in-memory ancient store data to disk
+// The server must have writes enabled
 func (d *DatabaseClient) Sync() error {
-	return errNotSupported
+	return d.SyncContext(d.ctx)
+}
+
+// SyncContext is Sync with a context, which cancels the request once done
+func (d *DatabaseClient) SyncContext(ctx context.Context) error {
+	return d.call(ctx, nil, "leveldb_sync")
 }
 
 // MigrateTable satisfies the ethdb.AncientWriter interface.
@@ -237,10 +617,79 @@ func (d *DatabaseClient) MigrateTable(string, func([]byte) ([]byte, error)) erro
 // NewSnapshot satisfies the ethdb.Snapshotter interface.
 // NewSnapshot creates a database snapshot based on the current state.
 func (d *DatabaseClient) NewSnapshot() (ethdb.Snapshot, error) {
-	return nil, errNotSupported
+	return d.NewSnapshotContext(d.ctx)
+}
+
+// NewSnapshotContext is NewSnapshot with a context, which cancels the request once done
+func (d *DatabaseClient) NewSnapshotContext(ctx context.Context) (ethdb.Snapshot, error) {
+	var (
+		id    rpc.ID
+		owner *endpoint
+	)
+	// a zero TTL selects the server's default; a retry after a lost response would leak the first snapshot
+	err := d.doOnce(ctx, nil, func(ctx context.Context, ep *endpoint) error {
+		owner = ep
+		return ep.client.CallContext(ctx, &id, "leveldb_newSnapshot", 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{client: d, ep: owner, id: id}, nil
 }
 
-// AncientDatadir returns an error as we don't have a backing chain freezer.
+// AncientDatadir satisfies the ethdb.AncientStater interface.
+// AncientDatadir returns the path of the freezer directory on the server.
+// The path refers to the server's filesystem and is only meaningful to processes running alongside it.
 func (d *DatabaseClient) AncientDatadir() (string, error) {
-	return "", errNotSupported
+	return d.AncientDatadirContext(d.ctx)
+}
+
+// AncientDatadirContext is AncientDatadir with a context, which cancels the request once done
+func (d *DatabaseClient) AncientDatadirContext(ctx context.Context) (string, error) {
+	var resp string
+	err := d.call(ctx, &resp, "leveldb_ancientDatadir")
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// Export returns the next chunk of at most limit key/value pairs with the given prefix, in key order
+// The export is started with a nil token and resumed with the Next token of the previous chunk until it is Done
+func (d *DatabaseClient) Export(prefix []byte, token []byte, limit int) (*leveldb_ethdb_rpc.ExportChunk, error) {
+	return d.ExportContext(d.ctx, prefix, token, limit)
+}
+
+// ExportContext is Export with a context, which cancels the request once done
+func (d *DatabaseClient) ExportContext(ctx context.Context, prefix []byte, token []byte, limit int) (*leveldb_ethdb_rpc.ExportChunk, error) {
+	var resp leveldb_ethdb_rpc.ExportChunk
+	err := d.call(ctx, &resp, "leveldb_export", prefix, token, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Keys) != len(resp.Values) {
+		return nil, fmt.Errorf("export chunk has %d keys but %d values", len(resp.Keys), len(resp.Values))
+	}
+
+	return &resp, nil
+}
+
+// Inspect returns the size and number of the entries of each category of data under the prefix, like geth's
+// db inspect; the server's last result for the prefix is returned unless refresh is set
+func (d *DatabaseClient) Inspect(prefix []byte, refresh bool) (*leveldb_ethdb_rpc.InspectResult, error) {
+	return d.InspectContext(d.ctx, prefix, refresh)
+}
+
+// InspectContext is Inspect with a context, which cancels the request once done
+// The scan carries on on the server if the request is cancelled, and its result is returned by the next call
+func (d *DatabaseClient) InspectContext(ctx context.Context, prefix []byte, refresh bool) (*leveldb_ethdb_rpc.InspectResult, error) {
+	var resp leveldb_ethdb_rpc.InspectResult
+	err := d.call(ctx, &resp, "leveldb_inspect", prefix, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
 }
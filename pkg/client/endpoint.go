@@ -0,0 +1,118 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// endpointCooldown is how long an endpoint that failed with a transient error is passed over for the others
+const endpointCooldown = 30 * time.Second
+
+var errClientClosed = errors.New("database client is closed")
+
+// endpoint is one of the server URLs a DatabaseClient sends its requests to, connected on first use
+type endpoint struct {
+	url string
+
+	mu     sync.Mutex // guards connecting and closed; client and binary don't change once set
+	client *rpc.Client
+	binary *binaryTransport
+	closed bool
+
+	// downUntil is the unix nano time until which the endpoint is cooling down after a failure
+	downUntil atomic.Int64
+}
+
+// dialer holds the settings the endpoints are connected with
+type dialer struct {
+	opts       []rpc.ClientOption
+	httpClient *http.Client
+	auth       rpc.HTTPAuth
+	binary     bool
+}
+
+// connect dials the endpoint and negotiates the binary transport, unless it is already connected
+// it fails once the endpoint is closed, as HTTP connections would otherwise keep serving requests
+func (ep *endpoint) connect(ctx context.Context, dialer *dialer) error {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return errClientClosed
+	}
+	if ep.client != nil {
+		return nil
+	}
+	client, err := rpc.DialOptions(ctx, ep.url, dialer.opts...)
+	if err != nil {
+		return err
+	}
+	if dialer.binary {
+		binary, err := negotiateBinaryTransport(ctx, client, ep.url, dialer.httpClient, dialer.auth)
+		if err != nil {
+			// the server couldn't be reached, negotiate again on the next attempt
+			client.Close()
+			return err
+		}
+		ep.binary = binary
+	}
+	ep.client = client
+	return nil
+}
+
+// markDown passes the endpoint over for the others until its cooldown ends
+func (ep *endpoint) markDown() {
+	ep.downUntil.Store(time.Now().Add(endpointCooldown).UnixNano())
+}
+
+// isDown reports whether the endpoint is cooling down after a failure
+func (ep *endpoint) isDown() bool {
+	return time.Now().UnixNano() < ep.downUntil.Load()
+}
+
+// pick returns the first endpoint, in the configured order, that isn't cooling down after a failure,
+// or the one whose cooldown ends first if all of them are
+func (d *DatabaseClient) pick() *endpoint {
+	next := d.endpoints[0]
+	for _, ep := range d.endpoints {
+		if !ep.isDown() {
+			return ep
+		}
+		if ep.downUntil.Load() < next.downUntil.Load() {
+			next = ep
+		}
+	}
+	return next
+}
+
+// close closes the connections of every endpoint
+func (d *DatabaseClient) close() {
+	for _, ep := range d.endpoints {
+		ep.mu.Lock()
+		if ep.client != nil {
+			ep.client.Close()
+		}
+		ep.closed = true
+		ep.mu.Unlock()
+	}
+}
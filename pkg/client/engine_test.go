@@ -0,0 +1,117 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+var testEngines = []string{leveldb_ethdb_rpc.EngineLevelDB, leveldb_ethdb_rpc.EnginePebble, leveldb_ethdb_rpc.EngineMemoryDB}
+
+// TestEngineDatabaseSuite runs geth's key-value store contract tests against a write-enabled server of each engine
+func TestEngineDatabaseSuite(t *testing.T) {
+	for _, engine := range testEngines {
+		t.Run(engine, func(t *testing.T) {
+			dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+				conf := &leveldb_ethdb_rpc.Config{Engine: engine, Cache: 16, Handles: 16, WriteEnabled: true}
+				if engine != leveldb_ethdb_rpc.EngineMemoryDB {
+					conf.FilePath = filepath.Join(t.TempDir(), "chaindata")
+				}
+				url := startTestHTTP(t, newTestServer(t, conf))
+				db, err := NewDatabaseClient(url)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return db
+			})
+		})
+	}
+}
+
+// TestEngineReads checks the reads of the key-value store and the freezer of each engine written on disk
+func TestEngineReads(t *testing.T) {
+	for _, engine := range []string{leveldb_ethdb_rpc.EngineLevelDB, leveldb_ethdb_rpc.EnginePebble} {
+		t.Run(engine, func(t *testing.T) {
+			conf := newTestConfig(t, engine)
+			// the engine is detected from the database directory
+			conf.Engine = ""
+			db := newTestClient(t, startTestHTTP(t, newTestServer(t, conf)))
+			checkTestKeys(t, db)
+
+			it := db.NewIterator([]byte("a01"), []byte("5"))
+			var keys int
+			for it.Next() {
+				if want := testKey(1500 + keys); !bytes.Equal(it.Key(), want) {
+					t.Fatalf("key %d: have %q, want %q", keys, it.Key(), want)
+				}
+				keys++
+			}
+			it.Release()
+			if it.Error() != nil || keys != 500 {
+				t.Fatalf("have %d keys, %v, want 500", keys, it.Error())
+			}
+
+			if frozen, err := db.Ancients(); err != nil || frozen != testAncients {
+				t.Fatalf("ancients: have %d, %v, want %d", frozen, err, testAncients)
+			}
+			if tail, err := db.Tail(); err != nil || tail != testTail {
+				t.Fatalf("tail: have %d, %v, want %d", tail, err, testTail)
+			}
+			if ok, err := db.HasAncient("headers", testTail-1); err != nil || ok {
+				t.Fatalf("truncated item: have %v, %v, want false", ok, err)
+			}
+			items, err := db.AncientRange("bodies", testTail, testAncients, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != testAncients-testTail {
+				t.Fatalf("have %d items, want %d", len(items), testAncients-testTail)
+			}
+			for i, item := range items {
+				if want := testAncient("bodies", uint64(testTail+i)); !bytes.Equal(item, want) {
+					t.Fatalf("item %d: have %q, want %q", testTail+i, item, want)
+				}
+			}
+		})
+	}
+}
+
+// TestMemoryDBReads checks that the memorydb engine serves the keys written to it through the server
+func TestMemoryDBReads(t *testing.T) {
+	conf := &leveldb_ethdb_rpc.Config{Engine: leveldb_ethdb_rpc.EngineMemoryDB, WriteEnabled: true}
+	db := newTestClient(t, startTestHTTP(t, newTestServer(t, conf)))
+	batch := db.NewBatch()
+	for i := 0; i < testKeys; i++ {
+		if err := batch.Put(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	checkTestKeys(t, db)
+	// without a freezer path the freezer is kept in memory too
+	if frozen, err := db.Ancients(); err != nil || frozen != 0 {
+		t.Fatalf("ancients: have %d, %v, want 0", frozen, err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	srpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg/rpc"
+)
+
+const (
+	// testKeys is the number of keys written to a test database
+	testKeys = 3000
+	// testAncients is the number of items appended to each table of a test freezer, of which testTail are truncated
+	testAncients = 10
+	testTail     = 3
+)
+
+var testTables = []string{"headers", "hashes", "bodies", "receipts", "diffs"}
+
+func testKey(i int) []byte   { return []byte(fmt.Sprintf("a%05d", i)) }
+func testValue(i int) []byte { return []byte(fmt.Sprintf("v%d", i)) }
+
+func testAncient(kind string, number uint64) []byte {
+	return []byte(fmt.Sprintf("%s-%d", kind, number))
+}
+
+// newTestConfig writes a database of the given engine with testKeys keys and a freezer of testAncients items
+// per table, the first testTail of which are truncated, and returns a read-only config serving it
+func newTestConfig(t testing.TB, engine string) *leveldb_ethdb_rpc.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chaindata")
+	ancient := filepath.Join(path, "ancient")
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: engine, Directory: path, AncientsDirectory: ancient, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < testKeys; i++ {
+		if err := db.Put(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < testAncients; i++ {
+			for _, kind := range testTables {
+				if err := op.AppendRaw(kind, i, testAncient(kind, i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.TruncateTail(testTail); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &leveldb_ethdb_rpc.Config{FilePath: path, FreezerPath: ancient, Engine: engine, Cache: 16, Handles: 16}
+}
+
+// newTestServer starts a server for the config, stopped when the test ends
+func newTestServer(t testing.TB, conf *leveldb_ethdb_rpc.Config) leveldb_ethdb_rpc.Server {
+	t.Helper()
+	srv, err := leveldb_ethdb_rpc.NewServer(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Serve(new(sync.WaitGroup))
+	t.Cleanup(func() { srv.Stop() })
+	return srv
+}
+
+// startTestHTTP serves the server's APIs and binary transport on a free local port, shut down when the test ends,
+// and returns the URL of the endpoint
+func startTestHTTP(t testing.TB, srv leveldb_ethdb_rpc.Server) string {
+	t.Helper()
+	return startTestHTTPS(t, srv, nil)
+}
+
+// startTestHTTPS is startTestHTTP serving HTTPS with tlsConfig, or plain HTTP if nil
+func startTestHTTPS(t testing.TB, srv leveldb_ethdb_rpc.Server, tlsConfig *tls.Config) string {
+	t.Helper()
+	return startTestHTTPWith(t, srv, tlsConfig, nil)
+}
+
+// startTestHTTPWith is startTestHTTPS with the JSON-RPC handler and the binary transport wrapped in middleware, if not nil
+func startTestHTTPWith(t testing.TB, srv leveldb_ethdb_rpc.Server, tlsConfig *tls.Config, middleware func(http.Handler) http.Handler) string {
+	t.Helper()
+	binary := srv.BinaryHandler()
+	if middleware != nil {
+		binary = middleware(binary)
+	}
+	httpSrv, _, err := srpc.StartHTTPEndpoint("127.0.0.1:0", srv.APIs(), []string{leveldb_ethdb_rpc.APIName}, nil, []string{"*"},
+		nil, tlsConfig, rpc.DefaultHTTPTimeouts, middleware, nil, srpc.Route{Path: leveldb_ethdb_rpc.BinaryPath, Handler: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { httpSrv.Shutdown(context.Background()) })
+	return httpSrv.Endpoint()
+}
+
+// newTestClient connects a DatabaseClient to url, closed when the test ends
+func newTestClient(t testing.TB, url string, opts ...Option) *DatabaseClient {
+	t.Helper()
+	db, err := NewDatabaseClient(url, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.(*DatabaseClient)
+}
+
+// checkTestKeys asserts that the test keys are read back, and that a missing key is reported as such
+func checkTestKeys(t testing.TB, db ethdb.KeyValueReader) {
+	t.Helper()
+	for _, i := range []int{0, 1, testKeys / 2, testKeys - 1} {
+		value, err := db.Get(testKey(i))
+		if err != nil {
+			t.Fatalf("get %s: %v", testKey(i), err)
+		}
+		if string(value) != string(testValue(i)) {
+			t.Fatalf("get %s: have %q, want %q", testKey(i), value, testValue(i))
+		}
+	}
+	if ok, err := db.Has([]byte("missing")); err != nil || ok {
+		t.Fatalf("has missing: have %v, %v, want false", ok, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	srpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg/rpc"
+)
+
+func TestIPC(t *testing.T) {
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	endpoint := filepath.Join(t.TempDir(), "leveldb.ipc")
+	ipc, _, err := srpc.StartIPCEndpoint(endpoint, srv.APIs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	db := newTestClient(t, endpoint)
+	checkTestKeys(t, db)
+
+	// connections are served concurrently
+	clients := make([]*DatabaseClient, 4)
+	for i := range clients {
+		clients[i] = newTestClient(t, endpoint)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(clients))
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *DatabaseClient) {
+			defer wg.Done()
+			value, err := c.Get(testKey(i))
+			if err == nil && string(value) != string(testValue(i)) {
+				t.Errorf("client %d: have %q, want %q", i, value, testValue(i))
+			}
+			errs <- err
+		}(i, c)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ipc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(endpoint); !os.IsNotExist(err) {
+		t.Fatalf("socket file not removed: %v", err)
+	}
+	if _, err := db.Get(testKey(0)); err == nil {
+		t.Fatal("expected requests to fail once the endpoint is closed")
+	}
+}
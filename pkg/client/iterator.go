@@ -0,0 +1,140 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// DefaultIteratorPageSize is the number of key/value pairs requested per page by the remote iterator
+const DefaultIteratorPageSize = 256
+
+var _ ethdb.Iterator = &iterator{}
+
+// iterator satisfies the ethdb.Iterator interface by lazily paging through a server-side iterator
+type iterator struct {
+	client *DatabaseClient
+	// ep is the endpoint holding the server-side iterator, or the snapshot it is opened on
+	ep *endpoint
+	// the method and arguments used to open the server-side iterator
+	openMethod string
+	openArgs   []interface{}
+
+	id     rpc.ID
+	opened bool
+	done   bool
+	keys   [][]byte
+	values [][]byte
+	pos    int
+	err    error
+}
+
+func newIterator(client *DatabaseClient, prefix []byte, start []byte) *iterator {
+	return &iterator{
+		client:     client,
+		openMethod: "leveldb_newIterator",
+		openArgs:   []interface{}{prefix, start},
+		pos:        -1,
+	}
+}
+
+// Next satisfies the ethdb.Iterator interface
+// Next moves the iterator to the next key/value pair, fetching a new page from the server when needed
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pos+1 < len(it.keys) {
+		it.pos++
+		return true
+	}
+	if it.done {
+		it.keys, it.values, it.pos = nil, nil, -1
+		return false
+	}
+	if err := it.fetch(); err != nil {
+		it.err = err
+		it.keys, it.values, it.pos = nil, nil, -1
+		return false
+	}
+	if len(it.keys) == 0 {
+		return false
+	}
+	it.pos = 0
+	return true
+}
+
+// fetch opens the server-side iterator if needed and loads the next page into the iterator
+func (it *iterator) fetch() error {
+	if !it.opened {
+		open := func(ctx context.Context, ep *endpoint) error {
+			it.ep = ep
+			return ep.client.CallContext(ctx, &it.id, it.openMethod, it.openArgs...)
+		}
+		// a retry after a lost response would leak the iterator opened by the first attempt
+		if err := it.client.doOnce(it.client.ctx, it.ep, open); err != nil {
+			return err
+		}
+		it.opened = true
+	}
+	var page leveldb_ethdb_rpc.IteratorPage
+	// a retry after a lost response would return the page after the lost one, so the iteration fails instead;
+	// the server-side iterator is released by Release, or by the server once it has gone idle
+	if err := it.client.callOnce(it.client.ctx, it.ep, &page, "leveldb_iteratorNext", it.id, DefaultIteratorPageSize); err != nil {
+		return err
+	}
+	it.keys, it.values, it.done = page.Keys, page.Values, page.Done
+	return nil
+}
+
+// Error satisfies the ethdb.Iterator interface
+// Error returns any accumulated error
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key satisfies the ethdb.Iterator interface
+// Key returns the key of the current key/value pair, or nil if done
+func (it *iterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+// Value satisfies the ethdb.Iterator interface
+// Value returns the value of the current key/value pair, or nil if done
+func (it *iterator) Value() []byte {
+	if it.pos < 0 || it.pos >= len(it.values) {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+// Release satisfies the ethdb.Iterator interface
+// Release releases the server-side iterator if it is still open
+func (it *iterator) Release() {
+	if it.opened && !it.done {
+		it.client.callOn(it.client.ctx, it.ep, nil, "leveldb_releaseIterator", it.id)
+	}
+	it.done = true
+	it.keys, it.values, it.pos = nil, nil, -1
+}
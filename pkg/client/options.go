@@ -0,0 +1,144 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Option configures a DatabaseClient
+type Option func(*options)
+
+// options holds the settings applied by the Options passed to NewDatabaseClient
+type options struct {
+	coalesceGets bool
+	cacheSize    uint64
+	binary       bool
+	jwtSecret    *[32]byte
+	jwtSubject   string
+	tlsConfig    *tls.Config
+	database     string
+	replicas     []string
+
+	timeout         time.Duration
+	retries         int
+	retryDelay      time.Duration
+	throttleRetries int
+	throttleDelay   time.Duration
+}
+
+// defaultOptions returns the settings used unless overridden by an Option
+func defaultOptions() options {
+	return options{
+		retryDelay:      DefaultRetryDelay,
+		throttleRetries: DefaultThrottleRetries,
+		throttleDelay:   DefaultThrottleDelay,
+	}
+}
+
+// WithGetCoalescing groups concurrent Get calls into a single leveldb_getMany request
+// Calls made while a request is in flight are queued and sent together as the next request
+// A request is cancelled once every call waiting on it has given up
+func WithGetCoalescing() Option {
+	return func(o *options) {
+		o.coalesceGets = true
+	}
+}
+
+// WithCache enables a read-through cache of at most size bytes for immutable data: entries keyed by
+// content hash (trie nodes, code, preimages, headers, bodies and receipts) and freezer items
+func WithCache(size uint64) Option {
+	return func(o *options) {
+		o.cacheSize = size
+	}
+}
+
+// WithBinaryTransport negotiates the RLP framed binary transport with the server, which is used instead of
+// JSON-RPC for Get, GetMany, Ancient and AncientRange; it only applies to HTTP endpoints and falls back to
+// JSON-RPC if the server doesn't offer it
+func WithBinaryTransport() Option {
+	return func(o *options) {
+		o.binary = true
+	}
+}
+
+// WithJWTSecret signs every HTTP request, and the websocket handshake, with a JWT derived from the secret
+// shared with the server
+func WithJWTSecret(secret [32]byte) Option {
+	return func(o *options) {
+		o.jwtSecret = &secret
+	}
+}
+
+// WithJWTSubject sets the subject of the JWTs signed with the secret set by WithJWTSecret,
+// which the server identifies the client by when applying rate limits
+func WithJWTSubject(subject string) Option {
+	return func(o *options) {
+		o.jwtSubject = subject
+	}
+}
+
+// WithTLSConfig dials https endpoints with the given TLS configuration, e.g. to trust a private CA
+// or to present a client certificate to a server that requires one
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithDatabase selects the named database of a server serving several, by appending its name to the URL path
+// of an HTTP or websocket endpoint; the default database is used if not set
+func WithDatabase(name string) Option {
+	return func(o *options) {
+		o.database = name
+	}
+}
+
+// WithThrottleBackoff sets how many times a request throttled by the server's rate limits is retried,
+// and the delay before the first retry, which doubles with every further retry; retries are off if 0
+func WithThrottleBackoff(retries int, delay time.Duration) Option {
+	return func(o *options) {
+		o.throttleRetries = retries
+		o.throttleDelay = delay
+	}
+}
+
+// WithTimeout bounds the time every request, including each of its retries, may take; no timeout if 0
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry sets how many times a request failing with a transient error, such as a refused connection, a
+// timeout or a 5xx response, is retried, and the delay before the first retry, which doubles with every
+// further retry; retries are off if 0. Writes are retried as well, which is safe as they are idempotent.
+func WithRetry(retries int, delay time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.retryDelay = delay
+	}
+}
+
+// WithReplicas adds URLs of servers holding the same data, which requests fail over to, in the given order,
+// when the server they were sent to fails with a transient error; a failed server is passed over for a while
+// before being tried again. Iterators and snapshots stay on the server they were opened on.
+func WithReplicas(urls ...string) Option {
+	return func(o *options) {
+		o.replicas = append(o.replicas, urls...)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	srpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg/rpc"
+)
+
+// TestRateLimits checks that the per-method limits apply to the websocket and IPC calls, which aren't throttled
+// by the HTTP handler
+func TestRateLimits(t *testing.T) {
+	for _, transport := range []string{"ws", "ipc"} {
+		t.Run(transport, func(t *testing.T) {
+			conf := newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)
+			conf.MethodRateLimits = map[string]float64{"leveldb_get": 0.01}
+			srv, err := leveldb_ethdb_rpc.NewServer(conf, leveldb_ethdb_rpc.NewRateLimiter(conf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.Serve(new(sync.WaitGroup))
+			t.Cleanup(func() { srv.Stop() })
+
+			var url string
+			if transport == "ws" {
+				ws, _, err := srpc.StartWSEndpoint("127.0.0.1:0", srv.APIs(), []string{leveldb_ethdb_rpc.APIName}, []string{"*"}, nil, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { ws.Shutdown(context.Background()) })
+				url = ws.Endpoint()
+			} else {
+				url = filepath.Join(t.TempDir(), "leveldb.ipc")
+				ipc, _, err := srpc.StartIPCEndpoint(url, srv.APIs())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { ipc.Close() })
+			}
+
+			db := newTestClient(t, url, WithThrottleBackoff(0, 0))
+			if _, err := db.Get(testKey(0)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Get(testKey(1)); !IsThrottled(err) {
+				t.Fatalf("have %v, want the second get to be throttled", err)
+			}
+			// the other methods aren't limited
+			if ok, err := db.Has(testKey(1)); err != nil || !ok {
+				t.Fatalf("has: have %v, %v, want true", ok, err)
+			}
+		})
+	}
+}
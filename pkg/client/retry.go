@@ -0,0 +1,198 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+const (
+	// DefaultThrottleRetries is how many times a request throttled by the server is retried by default
+	DefaultThrottleRetries = 5
+	// DefaultThrottleDelay is the default delay before the first retry of a throttled request
+	DefaultThrottleDelay = 100 * time.Millisecond
+	// DefaultRetryDelay is the default delay before the first retry of a request failing with a transient error
+	DefaultRetryDelay = 200 * time.Millisecond
+	// maxRetryDelay caps the exponentially growing delay between retries
+	maxRetryDelay = 30 * time.Second
+)
+
+// IsThrottled reports whether err is the server rejecting a request for exceeding one of its rate limits
+func IsThrottled(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == leveldb_ethdb_rpc.ThrottledErrorCode {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests
+}
+
+// isTransient reports whether err is a failure to reach the server, or to get an answer from it in time,
+// rather than an error returned by the server; errors after the caller's ctx is done aren't transient
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// connectError is the failure to connect to an endpoint, before any request was sent to it
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return e.err.Error() }
+func (e *connectError) Unwrap() error { return e.err }
+
+// unsent reports whether err is a failure that happened before the request could reach the server
+func unsent(err error) bool {
+	var connErr *connectError
+	return errors.As(err, &connErr) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryDelay returns the delay before the given retry, doubling from base with every retry
+func retryDelay(base time.Duration, retry int) time.Duration {
+	delay := base
+	for i := 0; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// do sends a request with fn to the first healthy endpoint, failing over to the others and retrying
+// transient errors and throttled requests as configured; throttled requests are rejected before being served,
+// so retrying them is safe for writes as well
+func (d *DatabaseClient) do(ctx context.Context, fn func(ctx context.Context, ep *endpoint) error) error {
+	return d.retry(ctx, nil, false, fn)
+}
+
+// doOn sends a request with fn to the given endpoint only, for requests on server-side state such as
+// iterators and snapshots, which the other endpoints don't hold
+func (d *DatabaseClient) doOn(ctx context.Context, ep *endpoint, fn func(ctx context.Context, ep *endpoint) error) error {
+	return d.retry(ctx, ep, false, fn)
+}
+
+// doOnce sends a request with fn like do, or to ep only if it is not nil, for requests that create or advance
+// server-side state: a transient error may come after the server has served the request, so it is only retried,
+// or failed over, if the request never reached the server; throttled requests are still retried
+func (d *DatabaseClient) doOnce(ctx context.Context, ep *endpoint, fn func(ctx context.Context, ep *endpoint) error) error {
+	return d.retry(ctx, ep, true, fn)
+}
+
+func (d *DatabaseClient) retry(ctx context.Context, pinned *endpoint, once bool, fn func(ctx context.Context, ep *endpoint) error) error {
+	var throttled, failed, failovers int
+	for {
+		ep := pinned
+		if ep == nil {
+			ep = d.pick()
+		}
+		err := d.attempt(ctx, ep, fn)
+		if err == nil {
+			return nil
+		}
+		var delay time.Duration
+		switch {
+		case IsThrottled(err):
+			if throttled >= d.throttleRetries {
+				return err
+			}
+			delay = retryDelay(d.throttleDelay, throttled)
+			throttled++
+		case isTransient(ctx, err) && (!once || unsent(err)):
+			if pinned == nil && len(d.endpoints) > 1 {
+				ep.markDown()
+				// every other endpoint is tried once before the retries kick in
+				if next := d.pick(); next != ep && !next.isDown() && failovers < len(d.endpoints)-1 {
+					log.WithError(err).Warnf("failing over from %s to %s", ep.url, next.url)
+					failovers++
+					continue
+				}
+			}
+			if failed >= d.retries {
+				return err
+			}
+			failed++
+			delay = retryDelay(d.retryDelay, failed-1)
+			log.WithError(err).Debugf("retrying request to %s in %s", ep.url, delay)
+		default:
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// attempt connects to the endpoint if needed and sends a single request with fn, within the per-call timeout
+func (d *DatabaseClient) attempt(ctx context.Context, ep *endpoint, fn func(ctx context.Context, ep *endpoint) error) error {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	if err := ep.connect(ctx, d.dialer); err != nil {
+		return &connectError{err}
+	}
+	return fn(ctx, ep)
+}
+
+// call sends a JSON-RPC request
+func (d *DatabaseClient) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		return ep.client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// callOnce sends a JSON-RPC request creating or advancing server-side state to the given endpoint only, see doOnce
+func (d *DatabaseClient) callOnce(ctx context.Context, ep *endpoint, result interface{}, method string, args ...interface{}) error {
+	return d.doOnce(ctx, ep, func(ctx context.Context, ep *endpoint) error {
+		return ep.client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// callOn sends a JSON-RPC request to the given endpoint only
+func (d *DatabaseClient) callOn(ctx context.Context, ep *endpoint, result interface{}, method string, args ...interface{}) error {
+	return d.doOn(ctx, ep, func(ctx context.Context, ep *endpoint) error {
+		return ep.client.CallContext(ctx, result, method, args...)
+	})
+}
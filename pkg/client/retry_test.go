@@ -0,0 +1,163 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// dropResponse serves the nth call of method, then answers it with 502 Bad Gateway as if its response was lost
+func dropResponse(method string, n int32) func(http.Handler) http.Handler {
+	var calls atomic.Int32
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(`"`+method+`"`)) && calls.Add(1) == n {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				http.Error(w, "response lost", http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestIteratorLostPage(t *testing.T) {
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	url := startTestHTTPWith(t, srv, nil, dropResponse("leveldb_iteratorNext", 2))
+	db := newTestClient(t, url, WithRetry(3, time.Millisecond))
+
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	n := 0
+	for ; it.Next(); n++ {
+		if want := testKey(n); !bytes.Equal(it.Key(), want) {
+			t.Fatalf("have key %s, want %s", it.Key(), want)
+		}
+	}
+	// the iteration fails with the lost page rather than skip its keys
+	if it.Error() == nil || n != DefaultIteratorPageSize {
+		t.Fatalf("have %d keys, %v, want %d and an error", n, it.Error(), DefaultIteratorPageSize)
+	}
+}
+
+// deadURL returns the URL of a local port nothing listens on
+func deadURL(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + l.Addr().String()
+	l.Close()
+	return url
+}
+
+// TestFailover checks that requests, including those creating server-side state, fail over from a dead endpoint
+// to a live one, which is then used until the dead one's cooldown ends
+func TestFailover(t *testing.T) {
+	srv := newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB))
+	db := newTestClient(t, deadURL(t), WithReplicas(startTestHTTP(t, srv)), WithRetry(0, 0))
+
+	checkTestKeys(t, db)
+	if !db.endpoints[0].isDown() || db.endpoints[1].isDown() {
+		t.Fatalf("have endpoints down %v and %v, want only the dead one", db.endpoints[0].isDown(), db.endpoints[1].isDown())
+	}
+
+	// the iterator is opened on the live endpoint, as the request never reached the dead one
+	db.endpoints[0].downUntil.Store(0)
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	n := 0
+	for ; it.Next(); n++ {
+	}
+	if it.Error() != nil || n != testKeys {
+		t.Fatalf("have %d keys, %v, want %d", n, it.Error(), testKeys)
+	}
+}
+
+// TestThrottledRetry checks that calls throttled by the server's rate limits are reported as such, with a
+// ThrottledErrorCode error over JSON-RPC and 429 Too Many Requests over the binary transport, and retried
+// after a backoff
+func TestThrottledRetry(t *testing.T) {
+	for _, binary := range []bool{false, true} {
+		name := "json"
+		if binary {
+			name = "binary"
+		}
+		t.Run(name, func(t *testing.T) {
+			conf := newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)
+			conf.RateLimit, conf.RateBurst = 5, 1
+			limiter := leveldb_ethdb_rpc.NewRateLimiter(conf)
+			srv, err := leveldb_ethdb_rpc.NewServer(conf, limiter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.Serve(new(sync.WaitGroup))
+			t.Cleanup(func() { srv.Stop() })
+			url := startTestHTTPWith(t, srv, nil, limiter.Handler)
+
+			var opts []Option
+			if binary {
+				opts = append(opts, WithBinaryTransport())
+			}
+			// the burst is used up by the first calls, some of which the client may make while connecting
+			db := newTestClient(t, url, append(opts, WithThrottleBackoff(0, 0))...)
+			for i := 0; i < 3 && err == nil; i++ {
+				_, err = db.Get(testKey(i))
+			}
+			if !IsThrottled(err) {
+				t.Fatalf("have %v, want a get throttled", err)
+			}
+			var (
+				rpcErr  rpc.Error
+				httpErr rpc.HTTPError
+			)
+			if binary && (!errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests) {
+				t.Fatalf("have %v, want 429 Too Many Requests", err)
+			}
+			if !binary && (!errors.As(err, &rpcErr) || rpcErr.ErrorCode() != leveldb_ethdb_rpc.ThrottledErrorCode) {
+				t.Fatalf("have %v, want error code %d", err, leveldb_ethdb_rpc.ThrottledErrorCode)
+			}
+
+			// a client backing off gets through once the bucket has refilled, even if it connects while throttled
+			db = newTestClient(t, url, append(opts, WithThrottleBackoff(5, 50*time.Millisecond))...)
+			for i := 0; i < 3; i++ {
+				if value, err := db.Get(testKey(i)); err != nil || !bytes.Equal(value, testValue(i)) {
+					t.Fatalf("get %d: have %q, %v, want %q", i, value, err, testValue(i))
+				}
+			}
+		})
+	}
+}
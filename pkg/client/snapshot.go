@@ -0,0 +1,82 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var _ ethdb.Snapshot = &Snapshot{}
+
+// Snapshot satisfies the ethdb.Snapshot interface using a snapshot held by the server
+// The server releases the snapshot once it goes unused for its TTL, so it should be released as soon as it is no longer needed
+type Snapshot struct {
+	client *DatabaseClient
+	// ep is the endpoint holding the snapshot
+	ep *endpoint
+	id rpc.ID
+
+	once sync.Once
+}
+
+// Has satisfies the ethdb.Snapshot interface
+// Has retrieves if a key is present in the snapshot
+func (s *Snapshot) Has(key []byte) (bool, error) {
+	var resp bool
+	err := s.client.callOn(s.client.ctx, s.ep, &resp, "leveldb_snapshotHas", s.id, key)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// Get satisfies the ethdb.Snapshot interface
+// Get retrieves the given key if it's present in the snapshot
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	var resp []byte
+	err := s.client.callOn(s.client.ctx, s.ep, &resp, "leveldb_snapshotGet", s.id, key)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// NewIterator creates a binary-alphabetical iterator over the snapshot content with a particular key prefix,
+// starting at a particular initial key (or after, if it does not exist)
+// the server can only open it as long as the database hasn't been written to through the server since the snapshot
+// was taken; otherwise the iterator yields nothing and its Error reports that the snapshot is stale
+func (s *Snapshot) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return &iterator{
+		client:     s.client,
+		ep:         s.ep,
+		openMethod: "leveldb_snapshotNewIterator",
+		openArgs:   []interface{}{s.id, prefix, start},
+		pos:        -1,
+	}
+}
+
+// Release satisfies the ethdb.Snapshot interface
+// Release releases the snapshot held by the server
+func (s *Snapshot) Release() {
+	s.once.Do(func() {
+		s.client.callOn(s.client.ctx, s.ep, nil, "leveldb_releaseSnapshot", s.id)
+	})
+}
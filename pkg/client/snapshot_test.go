@@ -0,0 +1,107 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+func TestSnapshotIterator(t *testing.T) {
+	conf := newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)
+	conf.WriteEnabled = true
+	db := newTestClient(t, startTestHTTP(t, newTestServer(t, conf)))
+
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+	it := snap.(*Snapshot).NewIterator(nil, nil)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	it.Release()
+	if it.Error() != nil || n != testKeys {
+		t.Fatalf("have %d keys, %v, want %d", n, it.Error(), testKeys)
+	}
+
+	// the snapshot itself still reads its state after a write, but can no longer be iterated
+	if err := db.Put(testKey(0), []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := snap.Get(testKey(0)); err != nil || string(value) != string(testValue(0)) {
+		t.Fatalf("snapshot get: have %q, %v, want %q", value, err, testValue(0))
+	}
+	it = snap.(*Snapshot).NewIterator(nil, nil)
+	if it.Next() || it.Error() == nil || !strings.Contains(it.Error().Error(), "changed since the snapshot") {
+		t.Fatalf("have %v, want a stale snapshot error", it.Error())
+	}
+	it.Release()
+}
+
+func TestSnapshotAndIteratorCaps(t *testing.T) {
+	conf := newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)
+	conf.MaxSnapshots = 2
+	conf.MaxIterators = 2
+	db := newTestClient(t, startTestHTTP(t, newTestServer(t, conf)))
+
+	var snaps []ethdb.Snapshot
+	for i := 0; i < conf.MaxSnapshots; i++ {
+		snap, err := db.NewSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, snap)
+	}
+	if _, err := db.NewSnapshot(); err == nil || !strings.Contains(err.Error(), "too many open snapshots") {
+		t.Fatalf("have %v, want too many open snapshots", err)
+	}
+	snaps[0].Release()
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatalf("snapshot after release: %v", err)
+	}
+	snap.Release()
+	snaps[1].Release()
+
+	// iterators are opened on the first call to Next, and held until exhausted or released
+	var its []ethdb.Iterator
+	for i := 0; i < conf.MaxIterators; i++ {
+		it := db.NewIterator(nil, nil)
+		if !it.Next() {
+			t.Fatal(it.Error())
+		}
+		its = append(its, it)
+	}
+	it := db.NewIterator(nil, nil)
+	if it.Next() || it.Error() == nil || !strings.Contains(it.Error().Error(), "too many open iterators") {
+		t.Fatalf("have %v, want too many open iterators", it.Error())
+	}
+	it.Release()
+	its[0].Release()
+	it = db.NewIterator(nil, nil)
+	if !it.Next() {
+		t.Fatalf("iterator after release: %v", it.Error())
+	}
+	it.Release()
+	its[1].Release()
+}
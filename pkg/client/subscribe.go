@@ -0,0 +1,66 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+)
+
+// SubscribeAncients subscribes to changes of the freezer item count and tail
+// The server must be reached over a transport that supports notifications (websocket or IPC)
+func (d *DatabaseClient) SubscribeAncients(ctx context.Context, ch chan<- *leveldb_ethdb_rpc.AncientsUpdate) (*rpc.ClientSubscription, error) {
+	ep, err := d.subscriber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ep.client.Subscribe(ctx, "leveldb", ch, "ancientsChanged")
+}
+
+// SubscribeKeys subscribes to changes of the values stored under the given keys
+// The server must be reached over a transport that supports notifications (websocket or IPC)
+func (d *DatabaseClient) SubscribeKeys(ctx context.Context, keys [][]byte, ch chan<- *leveldb_ethdb_rpc.KeyUpdate) (*rpc.ClientSubscription, error) {
+	ep, err := d.subscriber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ep.client.Subscribe(ctx, "leveldb", ch, "keysChanged", keys)
+}
+
+// SubscribeInspect inspects the database under the given prefix, see Inspect, and subscribes to the progress of
+// the scan; the last update holds its result, or its error
+// The server must be reached over a transport that supports notifications (websocket or IPC)
+func (d *DatabaseClient) SubscribeInspect(ctx context.Context, prefix []byte, refresh bool, ch chan<- *leveldb_ethdb_rpc.InspectProgress) (*rpc.ClientSubscription, error) {
+	ep, err := d.subscriber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ep.client.Subscribe(ctx, "leveldb", ch, "inspectProgress", prefix, refresh)
+}
+
+// subscriber returns the connected endpoint to subscribe on; subscriptions aren't failed over, it is up to
+// the caller to subscribe again once one ends with an error
+func (d *DatabaseClient) subscriber(ctx context.Context) (*endpoint, error) {
+	var sub *endpoint
+	err := d.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		sub = ep
+		return nil
+	})
+	return sub, err
+}
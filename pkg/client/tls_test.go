@@ -0,0 +1,161 @@
+// Copyright © 2022 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	leveldb_ethdb_rpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg"
+	srpc "github.com/cerc-io/leveldb-ethdb-rpc/pkg/rpc"
+)
+
+// testCert is a certificate and key written to PEM files
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert writes a certificate for name to dir, signed by parent, or self-signed if parent is nil
+// the certificate can sign others if ca is set, and is valid for 127.0.0.1
+func newTestCert(t *testing.T, dir, name string, parent *testCert, ca bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:         ca || parent == nil,
+
+		BasicConstraintsValid: true,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &testCert{cert: cert, key: key, certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+".key")}
+	if err := os.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+// newTestTLSClient connects a client trusting caFile and presenting the given certificate, if any, to url
+func newTestTLSClient(t *testing.T, url, caFile, certFile, keyFile string, opts ...Option) *DatabaseClient {
+	t.Helper()
+	tlsConfig, err := NewTLSConfig(caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestClient(t, url, append(opts, WithTLSConfig(tlsConfig), WithRetry(0, 0))...)
+}
+
+func TestSelfSignedTLS(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCert(t, dir, "server", nil, false)
+	tlsConfig, err := srpc.NewTLSConfig(server.certFile, server.keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startTestHTTPS(t, newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)), tlsConfig)
+	if !strings.HasPrefix(url, "https://") {
+		t.Fatalf("have %s, want an https endpoint", url)
+	}
+
+	// trusting the self-signed certificate
+	checkTestKeys(t, newTestTLSClient(t, url, server.certFile, "", ""))
+	binaryDB := newTestTLSClient(t, url, server.certFile, "", "", WithBinaryTransport())
+	checkTestKeys(t, binaryDB)
+	if binaryDB.endpoints[0].binary == nil {
+		t.Fatal("binary transport not negotiated over https")
+	}
+
+	// without trusting it
+	other := newTestCert(t, dir, "other", nil, false)
+	if _, err := newTestTLSClient(t, url, other.certFile, "", "").Get(testKey(0)); err == nil {
+		t.Fatal("expected a certificate signed by an untrusted CA to be refused")
+	}
+	if _, err := newTestClient(t, url, WithRetry(0, 0)).Get(testKey(0)); err == nil {
+		t.Fatal("expected a self-signed certificate to be refused by the system roots")
+	}
+	// plain http to the https endpoint
+	if _, err := newTestClient(t, "http"+strings.TrimPrefix(url, "https"), WithRetry(0, 0)).Get(testKey(0)); err == nil {
+		t.Fatal("expected plain http requests to fail")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, true)
+	server := newTestCert(t, dir, "server", ca, false)
+	client := newTestCert(t, dir, "client", ca, false)
+	tlsConfig, err := srpc.NewTLSConfig(server.certFile, server.keyFile, ca.certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startTestHTTPS(t, newTestServer(t, newTestConfig(t, leveldb_ethdb_rpc.EngineLevelDB)), tlsConfig)
+
+	checkTestKeys(t, newTestTLSClient(t, url, ca.certFile, client.certFile, client.keyFile))
+	checkTestKeys(t, newTestTLSClient(t, url, ca.certFile, client.certFile, client.keyFile, WithBinaryTransport()))
+
+	if _, err := newTestTLSClient(t, url, ca.certFile, "", "").Get(testKey(0)); err == nil {
+		t.Fatal("expected a client without a certificate to be refused")
+	}
+	otherCA := newTestCert(t, dir, "other-ca", nil, true)
+	other := newTestCert(t, dir, "other", otherCA, false)
+	if _, err := newTestTLSClient(t, url, ca.certFile, other.certFile, other.keyFile).Get(testKey(0)); err == nil {
+		t.Fatal("expected a client certificate signed by another CA to be refused")
+	}
+}
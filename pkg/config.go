@@ -18,8 +18,13 @@ package leveldb_ethdb_rpc
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/spf13/viper"
 )
 
@@ -29,12 +34,108 @@ type Config struct {
 	IPCEndpoint  string
 	HTTPEnabled  bool
 	HTTPEndpoint string
+	HTTPCors     []string
+	HTTPVhosts   []string
+	HTTPModules  []string
+	HTTPTimeouts rpc.HTTPTimeouts
+	WSEnabled    bool
+	WSEndpoint   string
+	// WSOrigins are the origins browsers may open websockets from, only localhost if empty
+	WSOrigins []string
+	WSModules []string
+	// JWTSecretPath is the file holding the hex encoded secret used to authenticate HTTP and WS requests;
+	// a new secret is generated and written to it if the file doesn't exist, authentication is off if empty
+	JWTSecretPath string
+	// TLSCertFile and TLSKeyFile turn on HTTPS for the HTTP endpoint; if TLSClientCAFile is also set,
+	// clients must present a certificate signed by one of its CAs
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	// MetricsEnabled turns on metrics collection, served in the Prometheus format on MetricsEndpoint
+	MetricsEnabled  bool
+	MetricsEndpoint string
+	// HealthStaleness is how long the head block may go without advancing before the server is reported not ready;
+	// the check is off if 0
+	HealthStaleness time.Duration
+	// ShutdownTimeout is how long in-flight HTTP requests are given to complete on shutdown
+	ShutdownTimeout time.Duration
+	// RateLimit is the number of calls per second allowed for each client, with bursts of up to RateBurst;
+	// MethodRateLimits further limit the calls per second of each client to single methods. Limits are off if 0.
+	RateLimit        float64
+	RateBurst        int
+	MethodRateLimits map[string]float64
+	// MaxAncientRangeCount and MaxAncientRangeBytes cap the items and bytes returned by a single ancient range read;
+	// like AncientRange, at least one item is returned even if it exceeds the byte cap. Caps are off if 0.
+	MaxAncientRangeCount uint64
+	MaxAncientRangeBytes uint64
+	// MaxConcurrentReads caps the number of calls served at the same time over all transports and databases,
+	// further calls wait for a slot
+	MaxConcurrentReads int
+	// MaxSnapshots and MaxIterators cap the number of snapshots and iterators held open for clients at the same time;
+	// opening more fails until some are released or expire. Caps are off if 0.
+	MaxSnapshots int
+	MaxIterators int
 
+	FilePath     string
+	Cache        int
+	Handles      int
+	FreezerPath  string
+	Namespace    string
+	WriteEnabled bool
+	Engine       string
+	// Follow reopens the read-only database every FollowInterval, and whenever its manifest changes, to pick up
+	// the changes of a replica updated in place; only the leveldb engine is supported. A running geth holds the
+	// directory lock, so its datadir can't be followed, and a reopen that fails is logged while the current view
+	// keeps serving
+	// the Cache and Handles allowance is split between the views that can be open at the same time
+	Follow         bool
+	FollowInterval time.Duration
+
+	// Name is the name of the database this config opens, empty for the default database
+	Name string
+	// Databases are additional named databases served next to the default one, each under its own URL path;
+	// they share the cache, handle, write and follow settings of the default database, Handles being the share
+	// of the process' file handle allowance of each database
+	Databases []DatabaseConfig
+}
+
+// DatabaseConfig holds the location of a named database
+type DatabaseConfig struct {
+	Name        string
 	FilePath    string
-	Cache       int
-	Handles     int
 	FreezerPath string
 	Namespace   string
+	Engine      string
+}
+
+// databaseNamePattern restricts database names to what can be used as a URL path segment
+var databaseNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// reservedDatabaseNames can't be used as database names, as they collide with the routes of the default database
+var reservedDatabaseNames = map[string]bool{
+	strings.TrimPrefix(BinaryPath, "/"): true,
+	strings.TrimPrefix(HealthPath, "/"): true,
+	strings.TrimPrefix(ReadyPath, "/"):  true,
+}
+
+// DatabasePath returns the URL path the named database is served on, empty for the default database
+func DatabasePath(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "/" + name
+}
+
+// ForDatabase returns the config opening the named database, with the shared settings of c
+func (c *Config) ForDatabase(db DatabaseConfig) *Config {
+	conf := *c
+	conf.Name = db.Name
+	conf.FilePath = db.FilePath
+	conf.FreezerPath = db.FreezerPath
+	conf.Namespace = db.Namespace
+	conf.Engine = db.Engine
+	conf.Databases = nil
+	return &conf
 }
 
 // NewConfig returns a new Config from viper parameters
@@ -43,29 +144,155 @@ func NewConfig() (*Config, error) {
 	viper.BindEnv(TOML_IPC_ENDPOINT, IPC_ENDPOINT)
 	viper.BindEnv(TOML_HTTP_ENABLED, HTTP_ENABLED)
 	viper.BindEnv(TOML_HTTP_ENDPOINT, HTTP_ENDPOINT)
+	viper.BindEnv(TOML_HTTP_CORS, HTTP_CORS)
+	viper.BindEnv(TOML_HTTP_VHOSTS, HTTP_VHOSTS)
+	viper.BindEnv(TOML_HTTP_MODULES, HTTP_MODULES)
+	viper.BindEnv(TOML_HTTP_READ_TIMEOUT, HTTP_READ_TIMEOUT)
+	viper.BindEnv(TOML_HTTP_WRITE_TIMEOUT, HTTP_WRITE_TIMEOUT)
+	viper.BindEnv(TOML_HTTP_IDLE_TIMEOUT, HTTP_IDLE_TIMEOUT)
+	viper.BindEnv(TOML_WS_ENABLED, WS_ENABLED)
+	viper.BindEnv(TOML_WS_ENDPOINT, WS_ENDPOINT)
+	viper.BindEnv(TOML_WS_ORIGINS, WS_ORIGINS)
+	viper.BindEnv(TOML_WS_MODULES, WS_MODULES)
+	viper.BindEnv(TOML_JWT_SECRET, JWT_SECRET)
+	viper.BindEnv(TOML_TLS_CERT, TLS_CERT)
+	viper.BindEnv(TOML_TLS_KEY, TLS_KEY)
+	viper.BindEnv(TOML_TLS_CLIENT_CA, TLS_CLIENT_CA)
+	viper.BindEnv(TOML_METRICS_ENABLED, METRICS_ENABLED)
+	viper.BindEnv(TOML_METRICS_ENDPOINT, METRICS_ENDPOINT)
+	viper.BindEnv(TOML_HEALTH_STALENESS, HEALTH_STALENESS)
+	viper.BindEnv(TOML_SHUTDOWN_TIMEOUT, SHUTDOWN_TIMEOUT)
+	viper.BindEnv(TOML_RATE_LIMIT, RATE_LIMIT)
+	viper.BindEnv(TOML_RATE_BURST, RATE_BURST)
+	viper.BindEnv(TOML_MAX_CONCURRENT_READS, MAX_CONCURRENT_READS)
+	viper.BindEnv(TOML_ANCIENT_RANGE_MAX_COUNT, ANCIENT_RANGE_MAX_COUNT)
+	viper.BindEnv(TOML_ANCIENT_RANGE_MAX_BYTES, ANCIENT_RANGE_MAX_BYTES)
+	viper.BindEnv(TOML_MAX_SNAPSHOTS, MAX_SNAPSHOTS)
+	viper.BindEnv(TOML_MAX_ITERATORS, MAX_ITERATORS)
 
 	viper.BindEnv(TOML_LEVELDB_PATH, LEVELDB_PATH)
 	viper.BindEnv(TOML_LEVELDB_CACHE_SIZE, LEVELDB_CACHE_SIZE)
 	viper.BindEnv(TOML_LEVELDB_ANCIENT_PATH, LEVELDB_ANCIENT_PATH)
 	viper.BindEnv(TOML_LEVELDB_NAMESPACE, LEVELDB_NAMESPACE)
+	viper.BindEnv(TOML_LEVELDB_WRITE_ENABLED, LEVELDB_WRITE_ENABLED)
+	viper.BindEnv(TOML_LEVELDB_ENGINE, LEVELDB_ENGINE)
+	viper.BindEnv(TOML_LEVELDB_FOLLOW, LEVELDB_FOLLOW)
+	viper.BindEnv(TOML_LEVELDB_FOLLOW_INTERVAL, LEVELDB_FOLLOW_INTERVAL)
 
 	numHandles, err := MakeDatabaseHandles()
 	if err != nil {
 		return nil, err
 	}
+	databases, err := databaseConfigs()
+	if err != nil {
+		return nil, err
+	}
+	// every database opened by the process gets an equal share of the allowance
+	opened := len(databases)
+	if viper.GetString(TOML_LEVELDB_PATH) != "" {
+		opened++
+	}
+	if opened > 1 {
+		numHandles /= opened
+	}
 	return &Config{
 		IPCEnabled:   viper.GetBool(TOML_IPC_ENABLED),
 		IPCEndpoint:  viper.GetString(TOML_IPC_ENDPOINT),
 		HTTPEnabled:  viper.GetBool(TOML_HTTP_ENABLED),
 		HTTPEndpoint: viper.GetString(TOML_HTTP_ENDPOINT),
-		FilePath:     viper.GetString(TOML_LEVELDB_PATH),
-		Cache:        viper.GetInt(TOML_LEVELDB_CACHE_SIZE),
-		Handles:      numHandles,
-		FreezerPath:  viper.GetString(TOML_LEVELDB_ANCIENT_PATH),
-		Namespace:    viper.GetString(TOML_LEVELDB_NAMESPACE),
+		HTTPCors:     splitList(viper.GetStringSlice(TOML_HTTP_CORS)),
+		HTTPVhosts:   splitList(viper.GetStringSlice(TOML_HTTP_VHOSTS)),
+		HTTPModules:  splitList(viper.GetStringSlice(TOML_HTTP_MODULES)),
+		HTTPTimeouts: rpc.HTTPTimeouts{
+			ReadTimeout:       viper.GetDuration(TOML_HTTP_READ_TIMEOUT),
+			ReadHeaderTimeout: viper.GetDuration(TOML_HTTP_READ_TIMEOUT),
+			WriteTimeout:      viper.GetDuration(TOML_HTTP_WRITE_TIMEOUT),
+			IdleTimeout:       viper.GetDuration(TOML_HTTP_IDLE_TIMEOUT),
+		},
+		WSEnabled:            viper.GetBool(TOML_WS_ENABLED),
+		WSEndpoint:           viper.GetString(TOML_WS_ENDPOINT),
+		WSOrigins:            splitList(viper.GetStringSlice(TOML_WS_ORIGINS)),
+		WSModules:            splitList(viper.GetStringSlice(TOML_WS_MODULES)),
+		JWTSecretPath:        viper.GetString(TOML_JWT_SECRET),
+		TLSCertFile:          viper.GetString(TOML_TLS_CERT),
+		TLSKeyFile:           viper.GetString(TOML_TLS_KEY),
+		TLSClientCAFile:      viper.GetString(TOML_TLS_CLIENT_CA),
+		MetricsEnabled:       viper.GetBool(TOML_METRICS_ENABLED),
+		MetricsEndpoint:      viper.GetString(TOML_METRICS_ENDPOINT),
+		HealthStaleness:      viper.GetDuration(TOML_HEALTH_STALENESS),
+		ShutdownTimeout:      viper.GetDuration(TOML_SHUTDOWN_TIMEOUT),
+		RateLimit:            viper.GetFloat64(TOML_RATE_LIMIT),
+		RateBurst:            viper.GetInt(TOML_RATE_BURST),
+		MethodRateLimits:     methodRateLimits(),
+		MaxConcurrentReads:   viper.GetInt(TOML_MAX_CONCURRENT_READS),
+		MaxAncientRangeCount: viper.GetUint64(TOML_ANCIENT_RANGE_MAX_COUNT),
+		MaxAncientRangeBytes: viper.GetUint64(TOML_ANCIENT_RANGE_MAX_BYTES),
+		MaxSnapshots:         viper.GetInt(TOML_MAX_SNAPSHOTS),
+		MaxIterators:         viper.GetInt(TOML_MAX_ITERATORS),
+		FilePath:             viper.GetString(TOML_LEVELDB_PATH),
+		Cache:                viper.GetInt(TOML_LEVELDB_CACHE_SIZE),
+		Handles:              numHandles,
+		FreezerPath:          viper.GetString(TOML_LEVELDB_ANCIENT_PATH),
+		Namespace:            viper.GetString(TOML_LEVELDB_NAMESPACE),
+		WriteEnabled:         viper.GetBool(TOML_LEVELDB_WRITE_ENABLED),
+		Engine:               viper.GetString(TOML_LEVELDB_ENGINE),
+		Follow:               viper.GetBool(TOML_LEVELDB_FOLLOW),
+		FollowInterval:       viper.GetDuration(TOML_LEVELDB_FOLLOW_INTERVAL),
+		Databases:            databases,
 	}, nil
 }
 
+// methodRateLimits reads the calls per second allowed for single methods from the [leveldb.methodRateLimits] table
+func methodRateLimits() map[string]float64 {
+	limits := make(map[string]float64)
+	for method := range viper.GetStringMap(TOML_METHOD_RATE_LIMITS) {
+		limits[method] = viper.GetFloat64(TOML_METHOD_RATE_LIMITS + "." + method)
+	}
+	return limits
+}
+
+// databaseConfigs reads the named databases from their [leveldb.databases.<name>] tables, sorted by name
+func databaseConfigs() ([]DatabaseConfig, error) {
+	tables := viper.GetStringMap(TOML_LEVELDB_DATABASES)
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	databases := make([]DatabaseConfig, 0, len(names))
+	for _, name := range names {
+		if !databaseNamePattern.MatchString(name) || reservedDatabaseNames[name] {
+			return nil, fmt.Errorf("invalid database name %q", name)
+		}
+		key := TOML_LEVELDB_DATABASES + "." + name + "."
+		db := DatabaseConfig{
+			Name:        name,
+			FilePath:    viper.GetString(key + "path"),
+			FreezerPath: viper.GetString(key + "ancient"),
+			Namespace:   viper.GetString(key + "namespace"),
+			Engine:      viper.GetString(key + "engine"),
+		}
+		if db.FilePath == "" {
+			return nil, fmt.Errorf("database %s has no path", name)
+		}
+		databases = append(databases, db)
+	}
+	return databases, nil
+}
+
+// splitList splits comma separated entries, as set through environment variables, into separate list items
+func splitList(list []string) []string {
+	var items []string
+	for _, entry := range list {
+		for _, item := range strings.Split(entry, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
 // MakeDatabaseHandles raises out the number of allowed file handles per process
 // for Geth and returns half of the allowance to assign to the database.
 func MakeDatabaseHandles() (int, error) {
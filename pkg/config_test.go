@@ -0,0 +1,60 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigSplitsHandles(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	allowance, err := MakeDatabaseHandles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		path      string
+		databases map[string]interface{}
+		opened    int
+	}{
+		{path: "/data/default", opened: 1},
+		{path: "/data/default", databases: map[string]interface{}{"second": map[string]interface{}{"path": "/data/second"}}, opened: 2},
+		{databases: map[string]interface{}{
+			"second": map[string]interface{}{"path": "/data/second"},
+			"third":  map[string]interface{}{"path": "/data/third"},
+		}, opened: 2},
+	} {
+		viper.Reset()
+		viper.Set(TOML_LEVELDB_PATH, test.path)
+		viper.Set(TOML_LEVELDB_DATABASES, test.databases)
+		conf, err := NewConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := allowance / test.opened; conf.Handles != want {
+			t.Fatalf("%d databases: have %d handles, want %d", test.opened, conf.Handles, want)
+		}
+		for _, db := range conf.Databases {
+			if have := conf.ForDatabase(db).Handles; have != conf.Handles {
+				t.Fatalf("database %s: have %d handles, want %d", db.Name, have, conf.Handles)
+			}
+		}
+	}
+}
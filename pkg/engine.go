@@ -0,0 +1,76 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	log "github.com/sirupsen/logrus"
+)
+
+// The storage engines that can back the server
+const (
+	EngineLevelDB  = "leveldb"
+	EnginePebble   = "pebble"
+	EngineMemoryDB = "memorydb"
+)
+
+// resolveEngine returns the storage engine to open for the given config
+// if no engine is configured, it is detected from the contents of the database directory, defaulting to leveldb
+func resolveEngine(conf *Config) (string, error) {
+	switch conf.Engine {
+	case EngineLevelDB, EnginePebble, EngineMemoryDB:
+		return conf.Engine, nil
+	case "":
+		if existing := rawdb.PreexistingDatabase(conf.FilePath); existing != "" {
+			log.Infof("detected %s database at %s", existing, conf.FilePath)
+			return existing, nil
+		}
+		return EngineLevelDB, nil
+	default:
+		return "", fmt.Errorf("unknown storage engine %q", conf.Engine)
+	}
+}
+
+// openKeyValueStore opens the key-value store backing the server with the given storage engine,
+// or the configured one if empty
+func openKeyValueStore(conf *Config, engine string, readonly bool) (ethdb.KeyValueStore, string, error) {
+	var err error
+	if engine == "" {
+		if engine, err = resolveEngine(conf); err != nil {
+			return nil, "", err
+		}
+	}
+	var db ethdb.KeyValueStore
+	switch engine {
+	case EngineLevelDB:
+		db, err = leveldb.New(conf.FilePath, conf.Cache, conf.Handles, conf.Namespace, readonly)
+	case EnginePebble:
+		db, err = pebble.New(conf.FilePath, conf.Cache, conf.Handles, conf.Namespace, readonly, false)
+	case EngineMemoryDB:
+		db = memorydb.New()
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return db, engine, nil
+}
@@ -24,11 +24,46 @@ const (
 	IPC_ENDPOINT  = "IPC_PATH"
 	HTTP_ENABLED  = "HTTP_ENABLED"
 	HTTP_ENDPOINT = "HTTP_PATH"
+	HTTP_CORS     = "HTTP_CORS"
+	HTTP_VHOSTS   = "HTTP_VHOSTS"
+	HTTP_MODULES  = "HTTP_MODULES"
 
-	LEVELDB_PATH         = "LEVELDB_PATH"
-	LEVELDB_CACHE_SIZE   = "LEVELDB_CACHE_SIZE"
-	LEVELDB_ANCIENT_PATH = "LEVELDB_ANCIENT_PATH"
-	LEVELDB_NAMESPACE    = "LEVELDB_NAMESPACE"
+	HTTP_READ_TIMEOUT  = "HTTP_READ_TIMEOUT"
+	HTTP_WRITE_TIMEOUT = "HTTP_WRITE_TIMEOUT"
+	HTTP_IDLE_TIMEOUT  = "HTTP_IDLE_TIMEOUT"
+
+	WS_ENABLED    = "WS_ENABLED"
+	WS_ENDPOINT   = "WS_PATH"
+	WS_ORIGINS    = "WS_ORIGINS"
+	WS_MODULES    = "WS_MODULES"
+	JWT_SECRET    = "JWT_SECRET"
+	TLS_CERT      = "TLS_CERT"
+	TLS_KEY       = "TLS_KEY"
+	TLS_CLIENT_CA = "TLS_CLIENT_CA"
+
+	METRICS_ENABLED  = "METRICS_ENABLED"
+	METRICS_ENDPOINT = "METRICS_PATH"
+	HEALTH_STALENESS = "HEALTH_STALENESS"
+	SHUTDOWN_TIMEOUT = "SHUTDOWN_TIMEOUT"
+
+	RATE_LIMIT           = "RATE_LIMIT"
+	RATE_BURST           = "RATE_BURST"
+	MAX_CONCURRENT_READS = "MAX_CONCURRENT_READS"
+
+	ANCIENT_RANGE_MAX_COUNT = "ANCIENT_RANGE_MAX_COUNT"
+	ANCIENT_RANGE_MAX_BYTES = "ANCIENT_RANGE_MAX_BYTES"
+
+	MAX_SNAPSHOTS = "MAX_SNAPSHOTS"
+	MAX_ITERATORS = "MAX_ITERATORS"
+
+	LEVELDB_PATH            = "LEVELDB_PATH"
+	LEVELDB_CACHE_SIZE      = "LEVELDB_CACHE_SIZE"
+	LEVELDB_ANCIENT_PATH    = "LEVELDB_ANCIENT_PATH"
+	LEVELDB_NAMESPACE       = "LEVELDB_NAMESPACE"
+	LEVELDB_WRITE_ENABLED   = "LEVELDB_WRITE_ENABLED"
+	LEVELDB_ENGINE          = "LEVELDB_ENGINE"
+	LEVELDB_FOLLOW          = "LEVELDB_FOLLOW"
+	LEVELDB_FOLLOW_INTERVAL = "LEVELDB_FOLLOW_INTERVAL"
 
 	TOML_LOGRUS_LEVEL = "log.level"
 	TOML_LOGRUS_FILE  = "log.file"
@@ -37,9 +72,46 @@ const (
 	TOML_IPC_ENDPOINT  = "leveldb.ipcPath"
 	TOML_HTTP_ENABLED  = "leveldb.httpEnabled"
 	TOML_HTTP_ENDPOINT = "leveldb.httpPath"
+	TOML_HTTP_CORS     = "leveldb.httpCors"
+	TOML_HTTP_VHOSTS   = "leveldb.httpVhosts"
+	TOML_HTTP_MODULES  = "leveldb.httpModules"
+
+	TOML_HTTP_READ_TIMEOUT  = "leveldb.httpReadTimeout"
+	TOML_HTTP_WRITE_TIMEOUT = "leveldb.httpWriteTimeout"
+	TOML_HTTP_IDLE_TIMEOUT  = "leveldb.httpIdleTimeout"
+
+	TOML_WS_ENABLED    = "leveldb.wsEnabled"
+	TOML_WS_ENDPOINT   = "leveldb.wsPath"
+	TOML_WS_ORIGINS    = "leveldb.wsOrigins"
+	TOML_WS_MODULES    = "leveldb.wsModules"
+	TOML_JWT_SECRET    = "leveldb.jwtSecret"
+	TOML_TLS_CERT      = "leveldb.tlsCert"
+	TOML_TLS_KEY       = "leveldb.tlsKey"
+	TOML_TLS_CLIENT_CA = "leveldb.tlsClientCA"
+
+	TOML_METRICS_ENABLED  = "leveldb.metricsEnabled"
+	TOML_METRICS_ENDPOINT = "leveldb.metricsPath"
+	TOML_HEALTH_STALENESS = "leveldb.healthStaleness"
+	TOML_SHUTDOWN_TIMEOUT = "leveldb.shutdownTimeout"
+
+	TOML_RATE_LIMIT           = "leveldb.rateLimit"
+	TOML_RATE_BURST           = "leveldb.rateBurst"
+	TOML_METHOD_RATE_LIMITS   = "leveldb.methodRateLimits"
+	TOML_MAX_CONCURRENT_READS = "leveldb.maxConcurrentReads"
+
+	TOML_ANCIENT_RANGE_MAX_COUNT = "leveldb.ancientRangeMaxCount"
+	TOML_ANCIENT_RANGE_MAX_BYTES = "leveldb.ancientRangeMaxBytes"
+
+	TOML_MAX_SNAPSHOTS = "leveldb.maxSnapshots"
+	TOML_MAX_ITERATORS = "leveldb.maxIterators"
 
-	TOML_LEVELDB_PATH         = "leveldb.path"
-	TOML_LEVELDB_CACHE_SIZE   = "leveldb.cacheSize"
-	TOML_LEVELDB_ANCIENT_PATH = "leveldb.ancient"
-	TOML_LEVELDB_NAMESPACE    = "leveldb.namespace"
+	TOML_LEVELDB_PATH            = "leveldb.path"
+	TOML_LEVELDB_CACHE_SIZE      = "leveldb.cacheSize"
+	TOML_LEVELDB_ANCIENT_PATH    = "leveldb.ancient"
+	TOML_LEVELDB_NAMESPACE       = "leveldb.namespace"
+	TOML_LEVELDB_WRITE_ENABLED   = "leveldb.writeEnabled"
+	TOML_LEVELDB_ENGINE          = "leveldb.engine"
+	TOML_LEVELDB_FOLLOW          = "leveldb.follow"
+	TOML_LEVELDB_FOLLOW_INTERVAL = "leveldb.followInterval"
+	TOML_LEVELDB_DATABASES       = "leveldb.databases"
 )
@@ -0,0 +1,83 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// MaxExportChunkSize is the maximum number of key/value pairs returned in a single export chunk
+	MaxExportChunkSize = 10000
+	// MaxExportChunkBytes is the size of the keys and values after which an export chunk is cut short
+	MaxExportChunkBytes = 4 * 1024 * 1024
+)
+
+// ExportChunk is a chunk of the key/value pairs under an exported prefix
+// Next is the continuation token to pass to the following Export call, unless Done reports the export is complete
+type ExportChunk struct {
+	Keys   [][]byte `json:"keys"`
+	Values [][]byte `json:"values"`
+	Next   []byte   `json:"next"`
+	Done   bool     `json:"done"`
+}
+
+// Export returns the next chunk of at most limit key/value pairs with the given prefix, in key order
+// The export is started with an empty token and resumed with the token of the previous chunk;
+// tokens don't depend on any server-side state, so an export can be resumed after a disconnect or restart
+func (s *PublicLevelDBAPI) Export(ctx context.Context, prefix []byte, token []byte, limit int) (*ExportChunk, error) {
+	done, err := s.begin(ctx, "export")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if limit <= 0 || limit > MaxExportChunkSize {
+		limit = MaxExportChunkSize
+	}
+	// the token is the start key, relative to the prefix, of the next chunk
+	it := s.b.NewIterator(prefix, token)
+	defer it.Release()
+
+	chunk := &ExportChunk{
+		Keys:   make([][]byte, 0, limit),
+		Values: make([][]byte, 0, limit),
+	}
+	size := 0
+	for len(chunk.Keys) < limit && size < MaxExportChunkBytes {
+		// stop reading once the client has gone away
+		if len(chunk.Keys)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !it.Next() {
+			chunk.Done = true
+			return chunk, it.Error()
+		}
+		key, value := common.CopyBytes(it.Key()), common.CopyBytes(it.Value())
+		chunk.Keys = append(chunk.Keys, key)
+		chunk.Values = append(chunk.Values, value)
+		size += len(key) + len(value)
+	}
+	if !it.Next() {
+		chunk.Done = true
+		return chunk, it.Error()
+	}
+	// resume from the first key that didn't fit in this chunk
+	chunk.Next = common.CopyBytes(it.Key()[len(prefix):])
+	return chunk, it.Error()
+}
@@ -0,0 +1,126 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestExportChunkSize(t *testing.T) {
+	entries := make(map[string]string, MaxExportChunkSize+10)
+	for i := 0; i < MaxExportChunkSize+10; i++ {
+		entries[fmt.Sprintf("k%05d", i)] = "v"
+	}
+	api := NewPublicLevelDBAPI(newTestBackend(t, newTestConfig(t, entries)), nil)
+
+	// limits above the maximum, or none at all, are clamped to the maximum
+	for _, limit := range []int{0, MaxExportChunkSize + 1} {
+		chunk, err := api.Export(context.Background(), []byte("k"), nil, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk.Keys) != MaxExportChunkSize || chunk.Done {
+			t.Fatalf("limit %d: have %d keys (done %v), want %d", limit, len(chunk.Keys), chunk.Done, MaxExportChunkSize)
+		}
+		if want := fmt.Sprintf("%05d", MaxExportChunkSize); string(chunk.Next) != want {
+			t.Fatalf("limit %d: have token %q, want %q", limit, chunk.Next, want)
+		}
+	}
+	chunk, err := api.Export(context.Background(), []byte("k"), nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Keys) != 3 || string(chunk.Next) != "00003" {
+		t.Fatalf("have %d keys and token %q, want 3 keys and token 00003", len(chunk.Keys), chunk.Next)
+	}
+}
+
+func TestExportChunkBytes(t *testing.T) {
+	// written through the server, as a read-only open fails to replay a journal holding values this large
+	conf := newTestConfig(t, nil)
+	conf.WriteEnabled = true
+	api := NewPublicLevelDBAPI(newTestBackend(t, conf), nil)
+	value := bytes.Repeat([]byte("v"), 1024*1024)
+	for i := 0; i < 6; i++ {
+		if err := api.Put(context.Background(), []byte(fmt.Sprintf("k%d", i)), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the chunk is cut short once its keys and values reach MaxExportChunkBytes
+	chunk, err := api.Export(context.Background(), []byte("k"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Keys) != 4 || chunk.Done || string(chunk.Next) != "4" {
+		t.Fatalf("have %d keys (done %v, token %q), want 4 keys and token 4", len(chunk.Keys), chunk.Done, chunk.Next)
+	}
+	chunk, err = api.Export(context.Background(), []byte("k"), chunk.Next, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Keys) != 2 || !chunk.Done || string(chunk.Keys[0]) != "k4" {
+		t.Fatalf("have %d keys from %q (done %v), want k4 and k5", len(chunk.Keys), chunk.Keys[0], chunk.Done)
+	}
+}
+
+// TestExportResume exports a prefix a chunk at a time, resuming each chunk from the token of the previous one,
+// and checks that every key under the prefix, and no other, is exported exactly once and in order
+func TestExportResume(t *testing.T) {
+	entries := map[string]string{"a": "outside", "c": "outside"}
+	for i := 0; i < 100; i++ {
+		entries[fmt.Sprintf("b%03d", i)] = fmt.Sprintf("v%d", i)
+	}
+	api := NewPublicLevelDBAPI(newTestBackend(t, newTestConfig(t, entries)), nil)
+
+	var (
+		keys   [][]byte
+		token  []byte
+		chunks int
+	)
+	for {
+		chunk, err := api.Export(context.Background(), []byte("b"), token, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks++
+		for i, key := range chunk.Keys {
+			if want := entries[string(key)]; string(chunk.Values[i]) != want {
+				t.Fatalf("key %q: have value %q, want %q", key, chunk.Values[i], want)
+			}
+		}
+		keys = append(keys, chunk.Keys...)
+		if chunk.Done {
+			break
+		}
+		token = chunk.Next
+	}
+	if chunks != 15 {
+		t.Errorf("have %d chunks, want 15", chunks)
+	}
+	if len(keys) != 100 {
+		t.Fatalf("have %d keys, want 100", len(keys))
+	}
+	for i, key := range keys {
+		if want := []byte(fmt.Sprintf("b%03d", i)); !bytes.Equal(key, want) {
+			t.Fatalf("key %d: have %q, want %q", i, key, want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// followDebounce is how long the follow loop waits after a manifest change before reopening the database,
+// so that a burst of changes results in a single reopen
+const followDebounce = 500 * time.Millisecond
+
+// maxFollowViews is the number of views that can be open at the same time in follow mode, the current one and those
+// still held by their users after a reopen; the cache and file handle allowance is split between them, and a reopen
+// is put off while the limit is reached
+const maxFollowViews = 4
+
+// dbView is an open key-value store and freezer, shared by the requests, iterators and snapshots reading from it
+// it is closed once the backend lets go of it and the last user has released it
+type dbView struct {
+	ethDB     ethdb.Database
+	refs      atomic.Int64
+	closeOnce sync.Once
+	// onClose is called once the view is closed, if set before the last reference is released
+	onClose func()
+}
+
+// openView opens the key-value store and the freezer with the given (or detected, if empty) storage engine
+// in follow mode each view gets its share of the configured cache and file handles
+func openView(conf *Config, engine string, readonly bool) (*dbView, string, error) {
+	if conf.Follow {
+		shared := *conf
+		shared.Cache = conf.Cache / maxFollowViews
+		shared.Handles = conf.Handles / maxFollowViews
+		conf = &shared
+	}
+	db, engine, err := openKeyValueStore(conf, engine, readonly)
+	if err != nil {
+		return nil, "", err
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(db, conf.FreezerPath, conf.Namespace, readonly)
+	if err != nil {
+		db.Close()
+		return nil, "", err
+	}
+	view := &dbView{ethDB: frdb}
+	view.refs.Store(1)
+	return view, engine, nil
+}
+
+// release drops a reference to the view, closing the database once no references are left
+func (v *dbView) release() error {
+	if v.refs.Add(-1) != 0 {
+		return nil
+	}
+	var err error
+	v.closeOnce.Do(func() {
+		err = v.ethDB.Close()
+		if v.onClose != nil {
+			v.onClose()
+		}
+	})
+	return err
+}
+
+// tryAcquire adds a reference to the view unless it is already closed, and reports whether it did
+func (v *dbView) tryAcquire() bool {
+	for {
+		refs := v.refs.Load()
+		if refs <= 0 {
+			return false
+		}
+		if v.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// viewIterator is an iterator that keeps its view open until it is released
+type viewIterator struct {
+	ethdb.Iterator
+	view *dbView
+	once sync.Once
+}
+
+func (it *viewIterator) Release() {
+	it.Iterator.Release()
+	it.once.Do(func() {
+		it.view.release()
+	})
+}
+
+// viewSnapshot is a snapshot that keeps its view open until it is released
+type viewSnapshot struct {
+	ethdb.Snapshot
+	view *dbView
+	once sync.Once
+}
+
+func (snap *viewSnapshot) Release() {
+	snap.Snapshot.Release()
+	snap.once.Do(func() {
+		snap.view.release()
+	})
+}
+
+// reopen opens a fresh read-only view of the database and swaps it in for the current one
+// requests, iterators and snapshots using the previous view keep it open until they are done with it
+func (s *LevelDBBackend) reopen() error {
+	view, _, err := openView(s.conf, s.engine, true)
+	if err != nil {
+		return err
+	}
+	s.writeLock.Lock()
+	if s.closed {
+		s.writeLock.Unlock()
+		return view.release()
+	}
+	s.viewLock.Lock()
+	old := s.view
+	s.view = view
+	s.viewLock.Unlock()
+	s.writeLock.Unlock()
+	// the backend's reference keeps the old view open until released below, so onClose is set before it can close
+	s.retiredViews.Add(1)
+	old.onClose = func() { s.retiredViews.Add(-1) }
+	return old.release()
+}
+
+// follow reopens the database on every interval, and shortly after the manifest of the key-value store changes,
+// until followQuit is closed; the interval is disabled if not positive
+// the database is only reopened if its files changed since last, their state when the current view was opened,
+// and while fewer than maxFollowViews views are open
+func (s *LevelDBBackend) follow(interval time.Duration, last fileState) {
+	defer close(s.followDone)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).Warn("failed to watch the database, following it on the interval only")
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(s.conf.FilePath); err != nil {
+			log.WithError(err).Warn("failed to watch the database, following it on the interval only")
+		} else {
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-tick:
+		case event := <-events:
+			if pending == nil && isManifestChange(event) {
+				pending = time.After(followDebounce)
+			}
+			continue
+		case <-pending:
+			pending = nil
+		case err := <-errs:
+			log.WithError(err).Debug("database watcher error")
+			continue
+		case <-s.followQuit:
+			return
+		}
+		// read before reopening, so that changes made while reopening are picked up by the next reopen
+		state, err := readFileState(s.conf.FilePath, s.conf.FreezerPath)
+		if err == nil && state == last {
+			continue
+		}
+		if retired := s.retiredViews.Load(); retired >= maxFollowViews-1 {
+			log.Debugf("putting off reopening the database, %d previous views are still in use", retired)
+			continue
+		}
+		if err := s.reopen(); err != nil {
+			log.WithError(err).Warn("failed to reopen the database")
+			continue
+		}
+		last = state
+		log.Debugf("reopened the database at %s", s.conf.FilePath)
+	}
+}
+
+// fileState summarises the files under the database directories, which change whenever the owning process writes
+// to the database: the write-ahead log grows, tables are flushed and compacted and freezer tables are appended to
+type fileState struct {
+	files   int
+	size    int64
+	modTime int64 // unix nano time of the latest modification
+}
+
+// readFileState reads the state of the files under the given directories, skipping empty paths
+func readFileState(dirs ...string) (fileState, error) {
+	var state fileState
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				// the file was removed by a compaction since the directory was read
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			state.files++
+			state.size += info.Size()
+			if modTime := info.ModTime().UnixNano(); modTime > state.modTime {
+				state.modTime = modTime
+			}
+			return nil
+		})
+		if err != nil {
+			return fileState{}, err
+		}
+	}
+	return state, nil
+}
+
+// isManifestChange reports whether the event changes the manifest of a leveldb or pebble database,
+// which happens whenever a memtable is flushed or tables are compacted
+func isManifestChange(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	return name == "CURRENT" || strings.HasPrefix(name, "MANIFEST-")
+}
@@ -0,0 +1,233 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+const testFollowInterval = 20 * time.Millisecond
+
+func newTestFollower(t *testing.T) *LevelDBBackend {
+	t.Helper()
+	conf := newTestConfig(t, map[string]string{"a": "1", "b": "2", "c": "3"})
+	conf.Follow = true
+	conf.FollowInterval = testFollowInterval
+	return newTestBackend(t, conf)
+}
+
+func currentView(b *LevelDBBackend) *dbView {
+	b.viewLock.RLock()
+	defer b.viewLock.RUnlock()
+	return b.view
+}
+
+// touch changes the files of the database directory, as a write of the owning process would
+func touch(t *testing.T, b *LevelDBBackend, n int) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(b.conf.FilePath, "touched"), []byte(fmt.Sprint(n)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForReopen waits until the current view is no longer v
+func waitForReopen(t *testing.T, b *LevelDBBackend, v *dbView) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); currentView(b) == v; {
+		if time.Now().After(deadline) {
+			t.Fatal("database not reopened")
+		}
+		time.Sleep(testFollowInterval)
+	}
+}
+
+func TestFollowReopensOnChange(t *testing.T) {
+	b := newTestFollower(t)
+	v := currentView(b)
+	time.Sleep(10 * testFollowInterval)
+	if currentView(b) != v {
+		t.Fatal("database reopened without changing")
+	}
+	touch(t, b, 0)
+	waitForReopen(t, b, v)
+	if value, err := b.Get([]byte("a")); err != nil || string(value) != "1" {
+		t.Fatalf("have %q, %v, want 1", value, err)
+	}
+}
+
+func TestFollowViewLimit(t *testing.T) {
+	b := newTestFollower(t)
+	var snaps []interface{ Release() }
+	for i := 0; i < maxFollowViews-1; i++ {
+		snap, err := b.NewSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, snap)
+		v := currentView(b)
+		touch(t, b, i)
+		waitForReopen(t, b, v)
+	}
+	if retired := b.retiredViews.Load(); retired != maxFollowViews-1 {
+		t.Fatalf("have %d retired views, want %d", retired, maxFollowViews-1)
+	}
+
+	// the reopen is put off while the old views are held
+	v := currentView(b)
+	touch(t, b, maxFollowViews)
+	time.Sleep(10 * testFollowInterval)
+	if currentView(b) != v {
+		t.Fatal("database reopened past the view limit")
+	}
+	for _, snap := range snaps {
+		snap.Release()
+	}
+	if retired := b.retiredViews.Load(); retired != 0 {
+		t.Fatalf("have %d retired views after releasing them, want 0", retired)
+	}
+	waitForReopen(t, b, v)
+}
+
+func TestSnapshotIteratorAfterReopen(t *testing.T) {
+	b := newTestFollower(t)
+	api := NewPublicLevelDBAPI(b, nil)
+	ctx := context.Background()
+	id, err := api.NewSnapshot(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := currentView(b)
+	touch(t, b, 0)
+	waitForReopen(t, b, v)
+
+	itID, err := api.SnapshotNewIterator(ctx, id, nil, nil)
+	if err != nil {
+		t.Fatalf("snapshot iterator after a reopen: %v", err)
+	}
+	page, err := api.IteratorNext(ctx, itID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Keys) != 3 || !page.Done {
+		t.Fatalf("have %d keys, done %v, want 3 keys", len(page.Keys), page.Done)
+	}
+
+	// the old view is closed once the snapshot and the iterator over it are released
+	itID, err = api.SnapshotNewIterator(ctx, id, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.ReleaseSnapshot(ctx, id)
+	if retired := b.retiredViews.Load(); retired != 1 {
+		t.Fatalf("have %d retired views while the iterator is open, want 1", retired)
+	}
+	api.ReleaseIterator(ctx, itID)
+	if retired := b.retiredViews.Load(); retired != 0 {
+		t.Fatalf("have %d retired views, want 0", retired)
+	}
+	if _, err := api.SnapshotNewIterator(ctx, id, nil, nil); err == nil {
+		t.Fatal("expected a released snapshot not to be iterated")
+	}
+}
+
+// copyFile copies src to dst through a temporary file renamed into place, as replication tools do,
+// so that views still reading the replaced file keep reading its old content
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), ".replica-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(out.Name(), dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replicate copies the key-value store files of the database at src over those of the database at dst, but its lock
+func replicate(t *testing.T, src, dst string) {
+	t.Helper()
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "LOCK" {
+			continue
+		}
+		copyFile(t, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()))
+	}
+}
+
+// TestFollowPicksUpWrites writes a key through a second handle on a replica of the followed database, as the node
+// the replica is taken from would, and checks that it becomes visible once the replica is copied in place
+func TestFollowPicksUpWrites(t *testing.T) {
+	b := newTestFollower(t)
+	replica := filepath.Join(t.TempDir(), "replica")
+	if err := os.Mkdir(replica, 0755); err != nil {
+		t.Fatal(err)
+	}
+	replicate(t, b.conf.FilePath, replica)
+
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: EngineLevelDB, Directory: replica, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte("d"), []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Has([]byte("d")); ok {
+		t.Fatal("key visible before the replica was copied")
+	}
+	replicate(t, replica, b.conf.FilePath)
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(testFollowInterval) {
+		if value, err := b.Get([]byte("d")); err == nil {
+			if string(value) != "4" {
+				t.Fatalf("have %q, want 4", value)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("key written after startup not visible")
+		}
+	}
+	if value, err := b.Get([]byte("a")); err != nil || string(value) != "1" {
+		t.Fatalf("have %q, %v, want 1", value, err)
+	}
+}
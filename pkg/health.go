@@ -0,0 +1,108 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	log "github.com/sirupsen/logrus"
+)
+
+// The paths the health and readiness handlers are served on
+const (
+	HealthPath = "/health"
+	ReadyPath  = "/ready"
+)
+
+// healthProbeKey is the key read to check that the database is open; it is the key of the head block hash
+var healthProbeKey = []byte("LastBlock")
+
+// HealthStatus reports the state of the database
+type HealthStatus struct {
+	Open            bool        `json:"open"`
+	Ready           bool        `json:"ready"`
+	HeadBlockHash   common.Hash `json:"headBlockHash"`
+	HeadBlockNumber uint64      `json:"headBlockNumber"`
+	Ancients        uint64      `json:"ancients"`
+	LastAdvance     time.Time   `json:"lastAdvance"`
+	Stale           bool        `json:"stale"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// healthMonitor tracks when the head block of the database last advanced
+type healthMonitor struct {
+	b *LevelDBBackend
+	// staleness is how long the head may go without advancing before the database is reported stale; 0 disables the check
+	staleness time.Duration
+
+	mu          sync.Mutex
+	head        common.Hash
+	lastAdvance time.Time
+}
+
+func newHealthMonitor(b *LevelDBBackend, staleness time.Duration) *healthMonitor {
+	return &healthMonitor{b: b, staleness: staleness, lastAdvance: time.Now()}
+}
+
+// status reads the current state of the database, recording a head change
+func (m *healthMonitor) status() *HealthStatus {
+	status := new(HealthStatus)
+	if _, err := m.b.Has(healthProbeKey); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Open = true
+	status.HeadBlockHash = rawdb.ReadHeadBlockHash(m.b)
+	if number := rawdb.ReadHeaderNumber(m.b, status.HeadBlockHash); number != nil {
+		status.HeadBlockNumber = *number
+	}
+	status.Ancients, _ = m.b.Ancients()
+
+	m.mu.Lock()
+	if status.HeadBlockHash != m.head {
+		m.head = status.HeadBlockHash
+		m.lastAdvance = time.Now()
+	}
+	status.LastAdvance = m.lastAdvance
+	m.mu.Unlock()
+
+	status.Stale = m.staleness > 0 && time.Since(status.LastAdvance) > m.staleness
+	status.Ready = status.HeadBlockHash != (common.Hash{}) && !status.Stale
+	return status
+}
+
+// handler returns an HTTP handler reporting the database status
+// it responds with 503 if the database is closed or, if ready is set, if it is not ready
+func (m *healthMonitor) handler(ready bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := m.status()
+		code := http.StatusOK
+		if !status.Open || (ready && !status.Ready) {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.WithError(err).Debug("failed to write health status")
+		}
+	})
+}
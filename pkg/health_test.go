@@ -0,0 +1,105 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// checkHealth calls the handler and checks its status code, returning the status it reported
+func checkHealth(t *testing.T, handler http.Handler, code int) *HealthStatus {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	status := new(HealthStatus)
+	if err := json.NewDecoder(rec.Body).Decode(status); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != code {
+		t.Fatalf("have status code %d (%+v), want %d", rec.Code, status, code)
+	}
+	return status
+}
+
+// writeHead writes a header at number and makes it the head block
+func writeHead(t *testing.T, b *LevelDBBackend, number int64) *types.Header {
+	t.Helper()
+	header := &types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(1)}
+	rawdb.WriteHeader(b, header)
+	rawdb.WriteHeadBlockHash(b, header.Hash())
+	return header
+}
+
+func TestHealth(t *testing.T) {
+	conf := newTestConfig(t, nil)
+	conf.WriteEnabled = true
+	b := newTestBackend(t, conf)
+	m := newHealthMonitor(b, 0)
+	health, ready := m.handler(false), m.handler(true)
+
+	// the database is open but has no head block yet
+	checkHealth(t, health, http.StatusOK)
+	if status := checkHealth(t, ready, http.StatusServiceUnavailable); !status.Open || status.Ready {
+		t.Fatalf("have %+v, want open and not ready", status)
+	}
+
+	head := writeHead(t, b, 5)
+	status := checkHealth(t, ready, http.StatusOK)
+	if !status.Ready || status.HeadBlockHash != head.Hash() || status.HeadBlockNumber != 5 {
+		t.Fatalf("have %+v, want ready at block 5", status)
+	}
+
+	b.Close()
+	if status := checkHealth(t, health, http.StatusServiceUnavailable); status.Open || status.Error == "" {
+		t.Fatalf("have %+v, want closed with an error", status)
+	}
+	checkHealth(t, ready, http.StatusServiceUnavailable)
+}
+
+func TestHealthStale(t *testing.T) {
+	conf := newTestConfig(t, nil)
+	conf.WriteEnabled = true
+	b := newTestBackend(t, conf)
+	m := newHealthMonitor(b, time.Minute)
+	health, ready := m.handler(false), m.handler(true)
+
+	writeHead(t, b, 1)
+	checkHealth(t, ready, http.StatusOK)
+
+	// the head hasn't advanced for longer than the staleness window
+	m.mu.Lock()
+	m.lastAdvance = time.Now().Add(-2 * time.Minute)
+	m.mu.Unlock()
+	if status := checkHealth(t, ready, http.StatusServiceUnavailable); !status.Stale || status.Ready {
+		t.Fatalf("have %+v, want stale", status)
+	}
+	// a stale database is still open
+	checkHealth(t, health, http.StatusOK)
+
+	head := writeHead(t, b, 2)
+	if status := checkHealth(t, ready, http.StatusOK); status.Stale || status.HeadBlockHash != head.Hash() {
+		t.Fatalf("have %+v, want ready at the new head", status)
+	}
+}
@@ -0,0 +1,56 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// newTestConfig writes a leveldb database holding the given key/value pairs, with an empty freezer,
+// and returns a read-only config serving it
+func newTestConfig(t *testing.T, entries map[string]string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chaindata")
+	ancient := filepath.Join(path, "ancient")
+	db, err := rawdb.Open(rawdb.OpenOptions{Type: EngineLevelDB, Directory: path, AncientsDirectory: ancient, Cache: 16, Handles: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range entries {
+		if err := db.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &Config{FilePath: path, FreezerPath: ancient, Engine: EngineLevelDB, Cache: 16, Handles: 16}
+}
+
+// newTestBackend opens a backend for the config, closed when the test ends
+func newTestBackend(t *testing.T, conf *Config) *LevelDBBackend {
+	t.Helper()
+	b, err := NewLevelDBBackend(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
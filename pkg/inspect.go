@@ -0,0 +1,461 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// inspectCacheSize is the number of prefixes whose last inspection result is kept
+	inspectCacheSize = 16
+	// inspectProgressInterval is how often the progress of a running inspection is pushed to subscribers
+	inspectProgressInterval = 2 * time.Second
+	// inspectLogInterval is how often the progress of a running inspection is logged
+	inspectLogInterval = 8 * time.Second
+)
+
+var (
+	// these mirror the unexported key prefixes and metadata keys of the rawdb schema
+	headerPrefix         = []byte("h") // headerPrefix + num + hash -> header
+	headerTDSuffix       = []byte("t") // headerPrefix + num + hash + headerTDSuffix -> td
+	headerHashSuffix     = []byte("n") // headerPrefix + num + headerHashSuffix -> hash
+	headerNumberPrefix   = []byte("H") // headerNumberPrefix + hash -> num
+	blockBodyPrefix      = []byte("b") // blockBodyPrefix + num + hash -> block body
+	blockReceiptsPrefix  = []byte("r") // blockReceiptsPrefix + num + hash -> block receipts
+	txLookupPrefix       = []byte("l") // txLookupPrefix + hash -> transaction lookup metadata
+	bloomBitsPrefix      = []byte("B") // bloomBitsPrefix + bit + section + hash -> bloom bits
+	skeletonHeaderPrefix = []byte("S") // skeletonHeaderPrefix + num -> header
+	stateIDPrefix        = []byte("L") // stateIDPrefix + state root -> state id
+	configPrefix         = []byte("ethereum-config-")
+	genesisPrefix        = []byte("ethereum-genesis-")
+
+	metadataKeys = [][]byte{
+		[]byte("DatabaseVersion"), []byte("LastHeader"), []byte("LastBlock"), []byte("LastFast"), []byte("LastFinalized"),
+		[]byte("LastPivot"), []byte("TrieSync"), []byte("SnapshotDisabled"), rawdb.SnapshotRootKey, []byte("SnapshotJournal"),
+		[]byte("SnapshotGenerator"), []byte("SnapshotRecovery"), []byte("TransactionIndexTail"), []byte("FastTransactionLookupLimit"),
+		[]byte("unclean-shutdown"), []byte("InvalidBlock"), []byte("eth2-transition"), []byte("SkeletonSyncStatus"),
+		[]byte("LastStateID"), []byte("TrieJournal"), []byte("SnapshotSyncStatus"), []byte("SnapSyncStatus"),
+	}
+)
+
+// the key-value store categories, in the order they are reported
+const (
+	inspectHeaders = iota
+	inspectBodies
+	inspectReceipts
+	inspectTDs
+	inspectNumHash
+	inspectHashNum
+	inspectTxLookups
+	inspectBloomBits
+	inspectCodes
+	inspectLegacyTries
+	inspectStateLookups
+	inspectAccountTries
+	inspectStorageTries
+	inspectPreimages
+	inspectAccountSnaps
+	inspectStorageSnaps
+	inspectBeaconHeaders
+	inspectCliqueSnaps
+	inspectMetadata
+	inspectChtTries
+	inspectBloomTries
+	inspectUnaccounted
+)
+
+// inspectCategories names the key-value store categories, as reported by geth's db inspect
+var inspectCategories = [...]struct{ database, category string }{
+	inspectHeaders:       {"Key-Value store", "Headers"},
+	inspectBodies:        {"Key-Value store", "Bodies"},
+	inspectReceipts:      {"Key-Value store", "Receipt lists"},
+	inspectTDs:           {"Key-Value store", "Difficulties"},
+	inspectNumHash:       {"Key-Value store", "Block number->hash"},
+	inspectHashNum:       {"Key-Value store", "Block hash->number"},
+	inspectTxLookups:     {"Key-Value store", "Transaction index"},
+	inspectBloomBits:     {"Key-Value store", "Bloombit index"},
+	inspectCodes:         {"Key-Value store", "Contract codes"},
+	inspectLegacyTries:   {"Key-Value store", "Hash trie nodes"},
+	inspectStateLookups:  {"Key-Value store", "Path trie state lookups"},
+	inspectAccountTries:  {"Key-Value store", "Path trie account nodes"},
+	inspectStorageTries:  {"Key-Value store", "Path trie storage nodes"},
+	inspectPreimages:     {"Key-Value store", "Trie preimages"},
+	inspectAccountSnaps:  {"Key-Value store", "Account snapshot"},
+	inspectStorageSnaps:  {"Key-Value store", "Storage snapshot"},
+	inspectBeaconHeaders: {"Key-Value store", "Beacon sync headers"},
+	inspectCliqueSnaps:   {"Key-Value store", "Clique snapshots"},
+	inspectMetadata:      {"Key-Value store", "Singleton metadata"},
+	inspectChtTries:      {"Light client", "CHT trie nodes"},
+	inspectBloomTries:    {"Light client", "Bloom trie nodes"},
+	inspectUnaccounted:   {"Key-Value store", "Unaccounted"},
+}
+
+// inspectFreezerTables are the chain freezer tables reported on when the whole database is inspected
+var inspectFreezerTables = []string{
+	rawdb.ChainFreezerHeaderTable,
+	rawdb.ChainFreezerHashTable,
+	rawdb.ChainFreezerBodiesTable,
+	rawdb.ChainFreezerReceiptTable,
+	rawdb.ChainFreezerDifficultyTable,
+}
+
+// InspectStat is the size and number of the entries of a single category
+type InspectStat struct {
+	Database string `json:"database"`
+	Category string `json:"category"`
+	Size     uint64 `json:"size"`
+	Count    uint64 `json:"count"`
+}
+
+// InspectResult is the outcome of scanning the keys under a prefix, categorised like geth's db inspect
+// The freezer tables are only reported on when the whole database is inspected
+type InspectResult struct {
+	Prefix []byte        `json:"prefix"`
+	Stats  []InspectStat `json:"stats"`
+	Keys   uint64        `json:"keys"`
+	Total  uint64        `json:"total"`
+	// Finished is when the scan completed, and Elapsed how long it took
+	Finished time.Time     `json:"finished"`
+	Elapsed  time.Duration `json:"elapsed"`
+}
+
+// InspectProgress is pushed to inspectProgress subscribers while the scan runs, and once more
+// with its Result, or its Error, when it is done
+type InspectProgress struct {
+	Keys    uint64         `json:"keys"`
+	Size    uint64         `json:"size"`
+	Elapsed time.Duration  `json:"elapsed"`
+	Result  *InspectResult `json:"result,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// inspection is a scan of the keys under a prefix, shared by every caller inspecting the prefix while it runs
+type inspection struct {
+	prefix  []byte
+	started time.Time
+	keys    atomic.Uint64
+	size    atomic.Uint64
+
+	// done is closed once result or err is set
+	done   chan struct{}
+	result *InspectResult
+	err    error
+}
+
+// progress returns how far the scan has got, along with its outcome once it is done
+func (i *inspection) progress() *InspectProgress {
+	p := &InspectProgress{Keys: i.keys.Load(), Size: i.size.Load(), Elapsed: time.Since(i.started)}
+	select {
+	case <-i.done:
+		p.Result = i.result
+		if i.err != nil {
+			p.Error = i.err.Error()
+		}
+	default:
+	}
+	return p
+}
+
+// inspector runs the inspections of the database in the background and caches their results by prefix
+type inspector struct {
+	b      *LevelDBBackend
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]*inspection
+	results *lru.Cache[string, *InspectResult]
+}
+
+func newInspector(b *LevelDBBackend) *inspector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &inspector{
+		b:       b,
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]*inspection),
+		results: lru.NewCache[string, *InspectResult](inspectCacheSize),
+	}
+}
+
+// cached returns the result of the last inspection of the prefix, if there is one
+func (in *inspector) cached(prefix []byte) (*InspectResult, bool) {
+	return in.results.Get(string(prefix))
+}
+
+// start returns the running inspection of the prefix, starting one if there is none
+func (in *inspector) start(prefix []byte) *inspection {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if scan, ok := in.running[string(prefix)]; ok {
+		return scan
+	}
+	scan := &inspection{prefix: common.CopyBytes(prefix), started: time.Now(), done: make(chan struct{})}
+	in.running[string(prefix)] = scan
+	in.wg.Add(1)
+	go in.run(scan)
+	return scan
+}
+
+func (in *inspector) run(scan *inspection) {
+	defer in.wg.Done()
+	log.Infof("inspecting the database under prefix %s", hexutil.Encode(scan.prefix))
+	result, err := inspectDatabase(in.ctx, in.b, scan)
+	if err != nil {
+		log.WithError(err).Warnf("inspecting the database under prefix %s failed", hexutil.Encode(scan.prefix))
+	} else {
+		log.Infof("inspected %d keys under prefix %s in %s", result.Keys, hexutil.Encode(scan.prefix), result.Elapsed)
+		in.results.Add(string(scan.prefix), result)
+	}
+	in.mu.Lock()
+	delete(in.running, string(scan.prefix))
+	in.mu.Unlock()
+	scan.result, scan.err = result, err
+	close(scan.done)
+}
+
+// stop cancels the running inspections and waits for them to return
+func (in *inspector) stop() {
+	in.cancel()
+	in.wg.Wait()
+}
+
+// inspectDatabase scans the keys under the prefix of the inspection, recording its progress as it goes
+func inspectDatabase(ctx context.Context, db ethdb.Database, scan *inspection) (*InspectResult, error) {
+	it := db.NewIterator(scan.prefix, nil)
+	defer it.Release()
+
+	var (
+		stats  [len(inspectCategories)]InspectStat
+		total  uint64
+		count  uint64
+		logged = time.Now()
+	)
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		size := uint64(len(key) + len(value))
+		stat := &stats[categorise(key, value)]
+		stat.Size += size
+		stat.Count++
+		total += size
+		count++
+		scan.keys.Store(count)
+		scan.size.Store(total)
+		if count%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if time.Since(logged) > inspectLogInterval {
+				log.Infof("inspecting the database under prefix %s: %d keys, %s", hexutil.Encode(scan.prefix), count, common.StorageSize(total))
+				logged = time.Now()
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	result := &InspectResult{Prefix: scan.prefix, Keys: count}
+	for i, stat := range stats {
+		if i == inspectUnaccounted && stat.Count == 0 {
+			continue
+		}
+		stat.Database, stat.Category = inspectCategories[i].database, inspectCategories[i].category
+		result.Stats = append(result.Stats, stat)
+	}
+	if len(scan.prefix) == 0 {
+		freezer, err := inspectFreezer(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, stat := range freezer {
+			total += stat.Size
+			result.Stats = append(result.Stats, stat)
+		}
+	}
+	result.Total = total
+	result.Finished = time.Now()
+	result.Elapsed = result.Finished.Sub(scan.started)
+	return result, nil
+}
+
+// inspectFreezer returns the size of every chain freezer table and the number of items they hold
+// a database without a freezer has none to report on
+func inspectFreezer(db ethdb.AncientReader) ([]InspectStat, error) {
+	ancients, err := db.Ancients()
+	if err != nil {
+		return nil, nil
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]InspectStat, 0, len(inspectFreezerTables))
+	for _, table := range inspectFreezerTables {
+		size, err := db.AncientSize(table)
+		if err != nil {
+			// the table isn't present in every freezer version
+			continue
+		}
+		stats = append(stats, InspectStat{Database: "Ancient store (Chain)", Category: strings.ToUpper(table[:1]) + table[1:], Size: size, Count: ancients - tail})
+	}
+	return stats, nil
+}
+
+// categorise returns the category of a key-value store entry, matching keys the same way as geth's db inspect
+func categorise(key, value []byte) int {
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == len(headerPrefix)+8+common.HashLength:
+		return inspectHeaders
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == len(blockBodyPrefix)+8+common.HashLength:
+		return inspectBodies
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+		return inspectReceipts
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
+		return inspectTDs
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
+		return inspectNumHash
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == len(headerNumberPrefix)+common.HashLength:
+		return inspectHashNum
+	case rawdb.IsLegacyTrieNode(key, value):
+		return inspectLegacyTries
+	case bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
+		return inspectStateLookups
+	case rawdb.IsAccountTrieNode(key):
+		return inspectAccountTries
+	case rawdb.IsStorageTrieNode(key):
+		return inspectStorageTries
+	case bytes.HasPrefix(key, rawdb.CodePrefix) && len(key) == len(rawdb.CodePrefix)+common.HashLength:
+		return inspectCodes
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == len(txLookupPrefix)+common.HashLength:
+		return inspectTxLookups
+	case bytes.HasPrefix(key, rawdb.SnapshotAccountPrefix) && len(key) == len(rawdb.SnapshotAccountPrefix)+common.HashLength:
+		return inspectAccountSnaps
+	case bytes.HasPrefix(key, rawdb.SnapshotStoragePrefix) && len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength:
+		return inspectStorageSnaps
+	case bytes.HasPrefix(key, rawdb.PreimagePrefix) && len(key) == len(rawdb.PreimagePrefix)+common.HashLength:
+		return inspectPreimages
+	case bytes.HasPrefix(key, configPrefix) && len(key) == len(configPrefix)+common.HashLength:
+		return inspectMetadata
+	case bytes.HasPrefix(key, genesisPrefix) && len(key) == len(genesisPrefix)+common.HashLength:
+		return inspectMetadata
+	case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+10+common.HashLength:
+		return inspectBloomBits
+	case bytes.HasPrefix(key, rawdb.BloomBitsIndexPrefix):
+		return inspectBloomBits
+	case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == len(skeletonHeaderPrefix)+8:
+		return inspectBeaconHeaders
+	case bytes.HasPrefix(key, rawdb.CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
+		return inspectCliqueSnaps
+	case bytes.HasPrefix(key, rawdb.ChtTablePrefix) ||
+		bytes.HasPrefix(key, rawdb.ChtIndexTablePrefix) ||
+		bytes.HasPrefix(key, rawdb.ChtPrefix):
+		return inspectChtTries
+	case bytes.HasPrefix(key, rawdb.BloomTrieTablePrefix) ||
+		bytes.HasPrefix(key, rawdb.BloomTrieIndexPrefix) ||
+		bytes.HasPrefix(key, rawdb.BloomTriePrefix):
+		return inspectBloomTries
+	}
+	for _, meta := range metadataKeys {
+		if bytes.Equal(key, meta) {
+			return inspectMetadata
+		}
+	}
+	return inspectUnaccounted
+}
+
+// Inspect returns the size and number of the entries of each category of data under the prefix, and of the
+// freezer tables if the prefix is empty; the last result for a prefix is returned unless refresh is set
+// If the call is cancelled the scan carries on, and its result is returned by the following call
+func (s *PublicLevelDBAPI) Inspect(ctx context.Context, prefix []byte, refresh bool) (*InspectResult, error) {
+	// the scan runs in the background, so the call doesn't hold a read slot while waiting for it
+	if err := s.limiter.admit(ctx, APIName+"_inspect"); err != nil {
+		return nil, err
+	}
+	if !refresh {
+		if result, ok := s.inspector.cached(prefix); ok {
+			return result, nil
+		}
+	}
+	scan := s.inspector.start(prefix)
+	select {
+	case <-scan.done:
+		return scan.result, scan.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InspectProgress creates a subscription (leveldb_subscribe "inspectProgress") that runs Inspect and is notified
+// with its progress every couple of seconds, and once more with its result when it is done
+func (s *PublicLevelDBAPI) InspectProgress(ctx context.Context, prefix []byte, refresh bool) (*rpc.Subscription, error) {
+	if err := s.limiter.admit(ctx, APIName+"_inspect"); err != nil {
+		return nil, err
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	if result, ok := s.inspector.cached(prefix); ok && !refresh {
+		go func() {
+			if err := notifier.Notify(sub.ID, &InspectProgress{Keys: result.Keys, Size: result.Total, Result: result}); err != nil {
+				log.WithError(err).Debug("failed to notify inspect subscriber")
+			}
+		}()
+		return sub, nil
+	}
+	scan := s.inspector.start(prefix)
+
+	go func() {
+		ticker := time.NewTicker(inspectProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-scan.done:
+			case <-sub.Err():
+				return
+			}
+			progress := scan.progress()
+			if err := notifier.Notify(sub.ID, progress); err != nil {
+				log.WithError(err).Debug("failed to notify inspect subscriber")
+				return
+			}
+			if progress.Result != nil || progress.Error != "" {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
@@ -0,0 +1,91 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"testing"
+)
+
+// hasFreezerStats reports whether the result holds the stats of the freezer tables
+func hasFreezerStats(result *InspectResult) bool {
+	for _, stat := range result.Stats {
+		if stat.Database == "Ancient store (Chain)" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInspectCache(t *testing.T) {
+	conf := newTestConfig(t, map[string]string{"a1": "1", "a2": "22", "b1": "3"})
+	conf.WriteEnabled = true
+	api := NewPublicLevelDBAPI(newTestBackend(t, conf), nil)
+	defer api.inspector.stop()
+	ctx := context.Background()
+
+	first, err := api.Inspect(ctx, []byte("a"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Keys != 2 || first.Total != 7 {
+		t.Fatalf("have %d keys of %d bytes, want 2 of 7", first.Keys, first.Total)
+	}
+	if hasFreezerStats(first) {
+		t.Fatal("freezer reported for a prefix")
+	}
+	api.inspector.mu.Lock()
+	running := len(api.inspector.running)
+	api.inspector.mu.Unlock()
+	if running != 0 {
+		t.Fatalf("have %d running inspections once done, want none", running)
+	}
+
+	// the cached result is returned until a refresh is asked for
+	if err := api.Put(ctx, []byte("a3"), []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := api.Inspect(ctx, []byte("a"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != first {
+		t.Fatal("cached result not returned")
+	}
+	refreshed, err := api.Inspect(ctx, []byte("a"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Keys != 3 {
+		t.Fatalf("have %d keys after a refresh, want 3", refreshed.Keys)
+	}
+	if cached, err := api.Inspect(ctx, []byte("a"), false); err != nil || cached != refreshed {
+		t.Fatalf("refreshed result not cached: %v", err)
+	}
+
+	// the freezer is reported when the whole database is inspected
+	whole, err := api.Inspect(ctx, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole.Keys != 4 || !hasFreezerStats(whole) {
+		t.Fatalf("have %d keys, freezer reported %v, want 4 keys and the freezer", whole.Keys, hasFreezerStats(whole))
+	}
+	if _, ok := api.inspector.cached(nil); !ok {
+		t.Fatal("result of the whole database not cached")
+	}
+}
@@ -0,0 +1,190 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxIteratorPageSize is the maximum number of key/value pairs returned in a single iterator page
+	MaxIteratorPageSize = 1024
+	// iteratorIdleTimeout is how long an open iterator may go unused before it is released by the server
+	iteratorIdleTimeout = 5 * time.Minute
+	// cancelCheckInterval is the number of pairs read between checks of whether the request was cancelled
+	cancelCheckInterval = 128
+)
+
+var (
+	errIteratorNotFound = errors.New("iterator not found")
+	errTooManyIterators = errors.New("too many open iterators")
+)
+
+// IteratorPage is a single page of key/value pairs read from a server-side iterator
+type IteratorPage struct {
+	Keys   [][]byte `json:"keys"`
+	Values [][]byte `json:"values"`
+	Done   bool     `json:"done"`
+}
+
+// remoteIterator wraps an ethdb.Iterator opened on behalf of a remote client
+type remoteIterator struct {
+	mu       sync.Mutex // guards it and released
+	it       ethdb.Iterator
+	released bool
+	lastUsed time.Time // guarded by the iteratorStore lock
+}
+
+// close releases the underlying iterator; the caller must hold the iterator lock
+func (r *remoteIterator) close() {
+	if !r.released {
+		r.it.Release()
+		r.released = true
+	}
+}
+
+// iteratorStore tracks the server-side iterators that are currently open, keyed by their ID
+type iteratorStore struct {
+	mu        sync.Mutex
+	iterators map[rpc.ID]*remoteIterator
+	// limit caps the number of iterators open at the same time, unlimited if 0
+	limit int
+}
+
+func newIteratorStore(limit int) *iteratorStore {
+	return &iteratorStore{iterators: make(map[rpc.ID]*remoteIterator), limit: limit}
+}
+
+// open registers a new iterator and returns its ID
+// the iterator is released right away if the store already holds as many iterators as its limit allows
+func (s *iteratorStore) open(it ethdb.Iterator) (rpc.ID, error) {
+	id := rpc.NewID()
+	s.mu.Lock()
+	if s.limit > 0 && len(s.iterators) >= s.limit {
+		s.mu.Unlock()
+		it.Release()
+		return "", errTooManyIterators
+	}
+	s.iterators[id] = &remoteIterator{it: it, lastUsed: time.Now()}
+	s.mu.Unlock()
+	return id, nil
+}
+
+// next reads up to limit key/value pairs from the iterator with the given ID
+// the iterator is released once it is exhausted or errors, or if ctx is done while reading the page,
+// as the pairs read so far would otherwise be skipped
+func (s *iteratorStore) next(ctx context.Context, id rpc.ID, limit int) (*IteratorPage, error) {
+	s.mu.Lock()
+	rit, ok := s.iterators[id]
+	if ok {
+		rit.lastUsed = time.Now()
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, errIteratorNotFound
+	}
+	if limit <= 0 || limit > MaxIteratorPageSize {
+		limit = MaxIteratorPageSize
+	}
+
+	rit.mu.Lock()
+	defer rit.mu.Unlock()
+	if rit.released {
+		return nil, errIteratorNotFound
+	}
+	page := &IteratorPage{
+		Keys:   make([][]byte, 0, limit),
+		Values: make([][]byte, 0, limit),
+	}
+	for len(page.Keys) < limit {
+		if len(page.Keys)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			s.forget(id)
+			rit.close()
+			return nil, ctx.Err()
+		}
+		if !rit.it.Next() {
+			page.Done = true
+			break
+		}
+		page.Keys = append(page.Keys, common.CopyBytes(rit.it.Key()))
+		page.Values = append(page.Values, common.CopyBytes(rit.it.Value()))
+	}
+	if page.Done {
+		err := rit.it.Error()
+		s.forget(id)
+		rit.close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// forget removes the iterator with the given ID from the store and returns it
+func (s *iteratorStore) forget(id rpc.ID) (*remoteIterator, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rit, ok := s.iterators[id]
+	delete(s.iterators, id)
+	return rit, ok
+}
+
+// release releases the iterator with the given ID, if it is still open
+func (s *iteratorStore) release(id rpc.ID) {
+	if rit, ok := s.forget(id); ok {
+		rit.mu.Lock()
+		rit.close()
+		rit.mu.Unlock()
+	}
+}
+
+// expire releases all iterators that have not been used within the idle timeout
+func (s *iteratorStore) expire() {
+	s.mu.Lock()
+	var stale []rpc.ID
+	for id, rit := range s.iterators {
+		if time.Since(rit.lastUsed) > iteratorIdleTimeout {
+			stale = append(stale, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range stale {
+		log.Debugf("releasing idle iterator %s", id)
+		s.release(id)
+	}
+}
+
+// releaseAll releases every open iterator
+func (s *iteratorStore) releaseAll() {
+	s.mu.Lock()
+	iterators := s.iterators
+	s.iterators = make(map[rpc.ID]*remoteIterator)
+	s.mu.Unlock()
+	for _, rit := range iterators {
+		rit.mu.Lock()
+		rit.close()
+		rit.mu.Unlock()
+	}
+}
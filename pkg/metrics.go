@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// metricsInterval is how often the backend gauges are refreshed while metrics are enabled
+const metricsInterval = 10 * time.Second
+
+// freezerTables are the chain freezer tables whose sizes are reported
+var freezerTables = []string{
+	rawdb.ChainFreezerHeaderTable,
+	rawdb.ChainFreezerHashTable,
+	rawdb.ChainFreezerBodiesTable,
+	rawdb.ChainFreezerReceiptTable,
+	rawdb.ChainFreezerDifficultyTable,
+}
+
+// levelDBProperties maps the numeric goleveldb properties to the gauges they are reported as
+// compaction and io stats are already reported by the leveldb driver itself under the database namespace
+var levelDBProperties = map[string]string{
+	"leveldb.cachedblock":  "leveldb/cache/blocks",
+	"leveldb.openedtables": "leveldb/cache/tables",
+	"leveldb.alivesnaps":   "leveldb/snapshots/alive",
+	"leveldb.aliveiters":   "leveldb/iterators/alive",
+}
+
+// collectMetrics updates the freezer and storage engine gauges from the backend
+func (s *LevelDBBackend) collectMetrics() {
+	if ancients, err := s.Ancients(); err == nil {
+		metrics.GetOrRegisterGauge(s.metricName("leveldb/ancient/items"), nil).Update(int64(ancients))
+	}
+	if tail, err := s.Tail(); err == nil {
+		metrics.GetOrRegisterGauge(s.metricName("leveldb/ancient/tail"), nil).Update(int64(tail))
+	}
+	for _, table := range freezerTables {
+		if size, err := s.AncientSize(table); err == nil {
+			metrics.GetOrRegisterGauge(s.metricName("leveldb/ancient/size/"+table), nil).Update(int64(size))
+		}
+	}
+	if s.engine != EngineLevelDB {
+		return
+	}
+	for property, name := range levelDBProperties {
+		stat, err := s.Stat(property)
+		if err != nil {
+			log.WithError(err).Debugf("failed to read %s", property)
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(stat), 10, 64)
+		if err != nil {
+			continue
+		}
+		metrics.GetOrRegisterGauge(s.metricName(name), nil).Update(value)
+	}
+}
+
+// metricName places the gauges of a named database under leveldb/<name>/, so they don't collide with those of the default one
+func (s *LevelDBBackend) metricName(name string) string {
+	if s.conf.Name == "" {
+		return name
+	}
+	return "leveldb/" + s.conf.Name + "/" + strings.TrimPrefix(name, "leveldb/")
+}
+
+// updateBinaryServeTime records the serving time of a binary transport request, like the RPC server does for JSON-RPC calls
+// requests for unknown methods are all recorded as binary/duration/unknown
+func updateBinaryServeTime(method string, success bool, elapsed time.Duration) {
+	if !metrics.Enabled {
+		return
+	}
+	if !binaryMethods[method] {
+		method = "unknown"
+	}
+	note := "success"
+	if !success {
+		note = "failure"
+	}
+	metrics.GetOrRegisterTimer(fmt.Sprintf("binary/duration/%s/%s", method, note), nil).Update(elapsed)
+}
@@ -0,0 +1,41 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestBinaryServeTimeUnknownMethod(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	updateBinaryServeTime(BinaryMethodGet, true, 0)
+	updateBinaryServeTime("made-up-method", false, 0)
+	if metrics.DefaultRegistry.Get("binary/duration/get/success") == nil {
+		t.Fatal("known method not timed")
+	}
+	if metrics.DefaultRegistry.Get("binary/duration/made-up-method/failure") != nil {
+		t.Fatal("unknown method timed under its own name")
+	}
+	if metrics.DefaultRegistry.Get("binary/duration/unknown/failure") == nil {
+		t.Fatal("unknown method not timed")
+	}
+}
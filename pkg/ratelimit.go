@@ -0,0 +1,336 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// ThrottledErrorCode is the JSON-RPC error code of requests rejected for exceeding a rate limit
+const ThrottledErrorCode = -32005
+
+// limiterSweepInterval is how often the buckets of clients that have gone idle are dropped
+const limiterSweepInterval = time.Minute
+
+// ThrottledError is returned to clients exceeding their rate limit for a method
+type ThrottledError struct {
+	Method string
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.Method)
+}
+
+// ErrorCode satisfies the rpc.Error interface
+func (e *ThrottledError) ErrorCode() int {
+	return ThrottledErrorCode
+}
+
+// RateLimiter throttles the calls of each client, identified by the subject of its JWT or else by its remote IP,
+// with a token bucket over all its calls and one per rate limited method. HTTP requests are throttled by Handler,
+// and websocket and IPC calls by the APIs it is passed to, which also have it cap how many calls are reading from
+// the databases at the same time, over every transport.
+type RateLimiter struct {
+	limit   rate.Limit
+	burst   int
+	methods map[string]rate.Limit
+	// subjects identifies clients by the subject of their JWT, only set if the JWTs are verified
+	subjects bool
+	// reads holds a token for every API call being served, nil if concurrency isn't capped
+	reads chan struct{}
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// clientLimiter holds the token buckets of a single client
+type clientLimiter struct {
+	all     *rate.Limiter
+	methods map[string]*rate.Limiter
+}
+
+// NewRateLimiter returns the RateLimiter configured by conf, or nil if neither rate limits nor a concurrency cap are set
+func NewRateLimiter(conf *Config) *RateLimiter {
+	if conf.RateLimit <= 0 && len(conf.MethodRateLimits) == 0 && conf.MaxConcurrentReads <= 0 {
+		return nil
+	}
+	l := &RateLimiter{
+		limit:     rate.Inf,
+		methods:   make(map[string]rate.Limit, len(conf.MethodRateLimits)),
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+		subjects:  conf.JWTSecretPath != "",
+	}
+	if conf.RateLimit > 0 {
+		l.limit = rate.Limit(conf.RateLimit)
+		l.burst = conf.RateBurst
+		if l.burst <= 0 {
+			l.burst = burstOf(conf.RateLimit)
+		}
+	}
+	for method, limit := range conf.MethodRateLimits {
+		if limit > 0 {
+			// method names are matched case-insensitively, as toml keys are lowercased when read
+			l.methods[strings.ToLower(method)] = rate.Limit(limit)
+		}
+	}
+	if conf.MaxConcurrentReads > 0 {
+		l.reads = make(chan struct{}, conf.MaxConcurrentReads)
+	}
+	return l
+}
+
+// burstOf returns the burst allowed by a limit of the given rate per second, a second worth of requests
+func burstOf(limit float64) int {
+	return int(math.Max(1, math.Ceil(limit)))
+}
+
+// Handler returns next wrapped with the rate limits, for the JSON-RPC handler and the binary transport
+// Throttled JSON-RPC calls are answered with a ThrottledErrorCode error, and binary transport requests
+// with 429 Too Many Requests
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBinaryRequestSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxBinaryRequestSize {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		binary := r.Header.Get("Content-Type") == BinaryContentType
+		if binary {
+			var req BinaryRequest
+			if err := rlp.DecodeBytes(body, &req); err == nil {
+				method := APIName + "_" + req.Method
+				if !l.allow(l.clientID(r), []string{method}) {
+					http.Error(w, (&ThrottledError{Method: method}).Error(), http.StatusTooManyRequests)
+					return
+				}
+			}
+		} else if calls, ok := parseCalls(body); ok {
+			methods := make([]string, len(calls))
+			for i, call := range calls {
+				methods[i] = call.Method
+			}
+			if !l.allow(l.clientID(r), methods) {
+				writeThrottled(w, calls, body)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// begin admits a call of method to the API, throttling websocket and IPC calls like Handler throttles HTTP requests,
+// and waits for a read slot if concurrent reads are capped; the returned function frees the slot once the call is done
+// HTTP calls and binary transport requests, whose context carries no transport, have already been throttled by Handler
+// A nil RateLimiter admits every call.
+func (l *RateLimiter) begin(ctx context.Context, method string) (func(), error) {
+	if err := l.admit(ctx, method); err != nil {
+		return nil, err
+	}
+	if l == nil || l.reads == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.reads <- struct{}{}:
+		return func() { <-l.reads }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// allow takes a token for every call from the client's buckets, and reports whether none of them was empty
+// A batch of more calls than a bucket holds takes all of its tokens, as it could otherwise never be allowed.
+func (l *RateLimiter) allow(client string, methods []string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > limiterSweepInterval {
+		l.sweep()
+		l.lastSweep = now
+	}
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{
+			all:     rate.NewLimiter(l.limit, l.burst),
+			methods: make(map[string]*rate.Limiter),
+		}
+		l.clients[client] = c
+	}
+	allowed := c.all.AllowN(now, min(len(methods), c.all.Burst()))
+	calls := make(map[string]int)
+	for _, method := range methods {
+		calls[strings.ToLower(method)]++
+	}
+	for method, n := range calls {
+		limit, ok := l.methods[method]
+		if !ok {
+			continue
+		}
+		ml, ok := c.methods[method]
+		if !ok {
+			ml = rate.NewLimiter(limit, burstOf(float64(limit)))
+			c.methods[method] = ml
+		}
+		if !ml.AllowN(now, min(n, ml.Burst())) {
+			allowed = false
+		}
+	}
+	if !allowed {
+		log.Debugf("throttled %d calls of %s", len(methods), client)
+	}
+	return allowed
+}
+
+// sweep drops the buckets of clients whose buckets are all full again; the caller must hold the lock
+func (l *RateLimiter) sweep() {
+	for client, c := range l.clients {
+		if c.all.Tokens() < float64(c.all.Burst()) {
+			continue
+		}
+		idle := true
+		for _, ml := range c.methods {
+			if ml.Tokens() < float64(ml.Burst()) {
+				idle = false
+				break
+			}
+		}
+		if idle {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// clientID identifies the client of a request by the subject of its JWT if it has one, and by its remote IP otherwise
+// the JWT has already been verified by the HTTP handler stack, subjects are ignored if authentication isn't enabled
+func (l *RateLimiter) clientID(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && l.subjects {
+		var claims jwt.RegisteredClaims
+		if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err == nil && claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// admit is begin without taking a read slot, for calls that wait on reads made in the background rather than
+// read themselves, which would otherwise hold a slot for as long as the background reads last
+func (l *RateLimiter) admit(ctx context.Context, method string) error {
+	if l == nil {
+		return nil
+	}
+	if info := rpc.PeerInfoFromContext(ctx); info.Transport == "ws" || info.Transport == "ipc" {
+		if !l.allow(peerID(info), []string{method}) {
+			return &ThrottledError{Method: method}
+		}
+	}
+	return nil
+}
+
+// peerID identifies the client of a websocket call by its remote IP, the JWT it was authenticated with not being
+// available to the API; IPC clients are all local and share their limits
+func peerID(info rpc.PeerInfo) string {
+	if info.Transport == "ipc" {
+		return "ipc"
+	}
+	host, _, err := net.SplitHostPort(info.RemoteAddr)
+	if err != nil {
+		host = info.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// jsonCall holds the fields of a JSON-RPC call the rate limits are applied on
+type jsonCall struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// parseCalls decodes the calls of a single or batch JSON-RPC request
+// malformed requests are left for the RPC handler to reject
+func parseCalls(body []byte) ([]jsonCall, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var calls []jsonCall
+		err := json.Unmarshal(body, &calls)
+		return calls, err == nil && len(calls) > 0
+	}
+	var call jsonCall
+	if err := json.Unmarshal(body, &call); err != nil {
+		return nil, false
+	}
+	return []jsonCall{call}, true
+}
+
+// jsonThrottled is the JSON-RPC response to a throttled call
+type jsonThrottled struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Error   struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// writeThrottled answers every call of a request with a ThrottledErrorCode error, as a batch if the request was one
+func writeThrottled(w http.ResponseWriter, calls []jsonCall, body []byte) {
+	responses := make([]jsonThrottled, len(calls))
+	for i, call := range calls {
+		responses[i].Version = "2.0"
+		responses[i].ID = call.ID
+		responses[i].Error.Code = ThrottledErrorCode
+		responses[i].Error.Message = (&ThrottledError{Method: call.Method}).Error()
+	}
+	var resp interface{} = responses
+	if trimmed := bytes.TrimSpace(body); trimmed[0] != '[' {
+		resp = responses[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.WithError(err).Debug("failed to write throttled response")
+	}
+}
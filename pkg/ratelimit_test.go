@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConcurrentReadsCap(t *testing.T) {
+	l := NewRateLimiter(&Config{MaxConcurrentReads: 1})
+	done, err := l.begin(context.Background(), "leveldb_get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// calls over any transport wait for the slot to be freed
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.begin(ctx, "chaindata_headBlockHeader"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("have %v, want the call to wait for the slot", err)
+	}
+	done()
+	done, err = l.begin(context.Background(), "leveldb_get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+
+	// a nil limiter admits every call
+	var none *RateLimiter
+	done, err = none.begin(context.Background(), "leveldb_get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+}
+
+func TestInspectHoldsNoReadSlot(t *testing.T) {
+	conf := newTestConfig(t, map[string]string{"a": "1"})
+	conf.MaxConcurrentReads = 1
+	limiter := NewRateLimiter(conf)
+	api := NewPublicLevelDBAPI(newTestBackend(t, conf), limiter)
+	defer api.inspector.stop()
+
+	done, err := limiter.begin(context.Background(), "leveldb_get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if result, err := api.Inspect(ctx, nil, false); err != nil || result.Keys != 1 {
+		t.Fatalf("have %v, %v, want the inspection not to wait for a read slot", result, err)
+	}
+}
+
+func TestRateLimitBatch(t *testing.T) {
+	l := NewRateLimiter(&Config{RateLimit: 0.01, RateBurst: 2, MethodRateLimits: map[string]float64{"leveldb_get": 0.01}})
+	handler := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served"))
+	}))
+	batch := `[` + strings.Repeat(`{"jsonrpc":"2.0","id":1,"method":"leveldb_get","params":["0x00"]},`, 4) +
+		`{"jsonrpc":"2.0","id":2,"method":"leveldb_has","params":["0x00"]}]`
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(batch))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// a batch larger than the bursts takes all of their tokens rather than being rejected outright
+	if w := post(); w.Body.String() != "served" {
+		t.Fatalf("have %q, want the batch to be served", w.Body.String())
+	}
+	var responses []jsonThrottled
+	if err := json.Unmarshal(post().Body.Bytes(), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 5 || responses[0].Error.Code != ThrottledErrorCode {
+		t.Fatalf("have %+v, want every call of the batch throttled", responses)
+	}
+}
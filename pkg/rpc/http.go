@@ -17,31 +17,112 @@
 package rpc
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/http"
 
-	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 	log "github.com/sirupsen/logrus"
 )
 
-// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules.
-func StartHTTPEndpoint(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) (*rpc.Server, error) {
+// Route is an additional HTTP handler served on the given path next to the RPC handler
+// Unauthenticated routes, such as health checks, are served without requiring a JWT
+type Route struct {
+	Path            string
+	Handler         http.Handler
+	Unauthenticated bool
+}
 
-	srv := rpc.NewServer()
-	err := node.RegisterApis(apis, modules, srv)
-	if err != nil {
-		utils.Fatalf("Could not register HTTP API: %w", err)
+// Database is a named database served next to the default one, with its RPC APIs on /<Name>
+// and its routes, whose paths are relative to /<Name>, below that path
+type Database struct {
+	Name   string
+	APIs   []rpc.API
+	Routes []Route
+}
+
+// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules/timeouts.
+// If jwtSecret is not empty, every request must carry a JWT signed with it.
+// If tlsConfig is not nil, the endpoint is served over HTTPS.
+// Any additional routes are served with the same cors/vhosts/jwt configuration.
+// The default database is served on / if apis is not empty, and every named database under its own path.
+// If middleware is not nil, it wraps the RPC handlers, inside the cors/vhosts/jwt handlers.
+func StartHTTPEndpoint(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, jwtSecret []byte, tlsConfig *tls.Config, timeouts rpc.HTTPTimeouts, middleware func(http.Handler) http.Handler, databases []Database, routes ...Route) (*HTTPServer, *rpc.Server, error) {
+
+	mux := http.NewServeMux()
+	handle := func(path string, handler http.Handler, unauthenticated bool) {
+		secret := jwtSecret
+		if unauthenticated {
+			secret = nil
+		}
+		mux.Handle(path, node.NewHTTPHandlerStack(handler, cors, vhosts, secret))
+		log.Debugf("HTTP route registered %s", path)
+	}
+	var srv *rpc.Server
+	var srvs []*rpc.Server
+	if len(apis) > 0 {
+		var err error
+		if srv, err = newRPCServer("HTTP", apis, modules); err != nil {
+			return nil, nil, err
+		}
+		srvs = append(srvs, srv)
+		handle("/", wrap(srv, middleware), false)
+		for _, route := range routes {
+			handle(route.Path, route.Handler, route.Unauthenticated)
+		}
+	}
+	for _, db := range databases {
+		dbSrv, err := newRPCServer("HTTP", db.APIs, modules)
+		if err != nil {
+			stopAll(srvs)
+			return nil, nil, err
+		}
+		srvs = append(srvs, dbSrv)
+		handle("/"+db.Name, wrap(dbSrv, middleware), false)
+		for _, route := range db.Routes {
+			handle("/"+db.Name+route.Path, route.Handler, route.Unauthenticated)
+		}
+		log.Infof("HTTP serving database %s on /%s", db.Name, db.Name)
 	}
-	handler := node.NewHTTPHandlerStack(srv, cors, vhosts, nil)
 
 	// start http server
-	_, addr, err := node.StartHTTPEndpoint(endpoint, rpc.DefaultHTTPTimeouts, handler)
+	httpSrv, addr, err := startHTTPServer(endpoint, timeouts, mux, tlsConfig)
 	if err != nil {
-		utils.Fatalf("Could not start RPC api: %v", err)
+		stopAll(srvs)
+		return nil, nil, fmt.Errorf("could not start RPC api: %w", err)
 	}
-	extapiURL := fmt.Sprintf("http://%v/", addr)
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	extapiURL := fmt.Sprintf("%s://%v/", scheme, addr)
 	log.Infof("HTTP endpoint opened %s", extapiURL)
 
-	return srv, err
+	return &HTTPServer{name: "HTTP", endpoint: extapiURL, server: httpSrv, srvs: srvs}, srv, nil
+}
+
+// wrap returns handler wrapped with middleware, if there is one
+func wrap(handler http.Handler, middleware func(http.Handler) http.Handler) http.Handler {
+	if middleware == nil {
+		return handler
+	}
+	return middleware(handler)
+}
+
+// newRPCServer returns an RPC server with the given modules of the APIs registered
+func newRPCServer(name string, apis []rpc.API, modules []string) (*rpc.Server, error) {
+	srv := rpc.NewServer()
+	if err := node.RegisterApis(apis, modules, srv); err != nil {
+		srv.Stop()
+		return nil, fmt.Errorf("could not register %s API: %w", name, err)
+	}
+	return srv, nil
+}
+
+// stopAll stops the RPC servers of an endpoint that failed to start
+func stopAll(srvs []*rpc.Server) {
+	for _, srv := range srvs {
+		srv.Stop()
+	}
 }
@@ -0,0 +1,177 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type testService struct{}
+
+func (testService) Echo(s string) string { return s }
+
+// Sleep returns once d has elapsed or the request is cancelled
+func (testService) Sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var testAPIs = []rpc.API{
+	{Namespace: "test", Service: testService{}},
+	{Namespace: "hidden", Service: testService{}},
+}
+
+// startTestEndpoint serves testAPIs over HTTP on a free local port, shut down when the test ends
+func startTestEndpoint(t *testing.T, cors, vhosts []string, timeouts rpc.HTTPTimeouts) *HTTPServer {
+	t.Helper()
+	srv, _, err := StartHTTPEndpoint("127.0.0.1:0", testAPIs, []string{"test"}, cors, vhosts, nil, nil, timeouts, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return srv
+}
+
+// post sends a JSON-RPC request calling method with the given host, if not empty, and headers, and returns the response
+func post(t *testing.T, url, host, method string, header http.Header) *http.Response {
+	t.Helper()
+	body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":["hello"]}`
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "" {
+		req.Host = host
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHTTPCors(t *testing.T) {
+	srv := startTestEndpoint(t, []string{"http://allowed.example"}, []string{"*"}, rpc.DefaultHTTPTimeouts)
+
+	resp := post(t, srv.Endpoint(), "", "test_echo", http.Header{"Origin": {"http://allowed.example"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("allowed origin: have status %d, want 200", resp.StatusCode)
+	}
+	if have := resp.Header.Get("Access-Control-Allow-Origin"); have != "http://allowed.example" {
+		t.Fatalf("allowed origin: have Access-Control-Allow-Origin %q", have)
+	}
+
+	resp = post(t, srv.Endpoint(), "", "test_echo", http.Header{"Origin": {"http://other.example"}})
+	if have := resp.Header.Get("Access-Control-Allow-Origin"); have != "" {
+		t.Fatalf("other origin: have Access-Control-Allow-Origin %q, want none", have)
+	}
+
+	req, err := http.NewRequest(http.MethodOptions, srv.Endpoint(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "http://allowed.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	preflight, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preflight.Body.Close()
+	if have := preflight.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(have, http.MethodPost) {
+		t.Fatalf("preflight: have Access-Control-Allow-Methods %q, want POST", have)
+	}
+}
+
+func TestHTTPVhosts(t *testing.T) {
+	srv := startTestEndpoint(t, nil, []string{"db.example"}, rpc.DefaultHTTPTimeouts)
+
+	if resp := post(t, srv.Endpoint(), "db.example", "test_echo", http.Header{}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("allowed host: have status %d, want 200", resp.StatusCode)
+	}
+	if resp := post(t, srv.Endpoint(), "other.example", "test_echo", http.Header{}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("other host: have status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestHTTPModules(t *testing.T) {
+	srv := startTestEndpoint(t, nil, []string{"*"}, rpc.DefaultHTTPTimeouts)
+	client, err := rpc.Dial(srv.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var echo string
+	if err := client.Call(&echo, "test_echo", "hello"); err != nil || echo != "hello" {
+		t.Fatalf("test_echo: have %q, %v", echo, err)
+	}
+	if err := client.Call(&echo, "hidden_echo", "hello"); err == nil {
+		t.Fatal("expected a module that isn't enabled not to be served")
+	}
+}
+
+func TestHTTPTimeouts(t *testing.T) {
+	timeouts := rpc.HTTPTimeouts{
+		ReadTimeout:       2 * time.Second,
+		ReadHeaderTimeout: 3 * time.Second,
+		WriteTimeout:      time.Second,
+		IdleTimeout:       4 * time.Second,
+	}
+	srv := startTestEndpoint(t, nil, []string{"*"}, timeouts)
+	if have := srv.server; have.ReadTimeout != timeouts.ReadTimeout || have.ReadHeaderTimeout != timeouts.ReadHeaderTimeout ||
+		have.WriteTimeout != timeouts.WriteTimeout || have.IdleTimeout != timeouts.IdleTimeout {
+		t.Fatalf("have timeouts read %v, read header %v, write %v, idle %v, want %+v",
+			have.ReadTimeout, have.ReadHeaderTimeout, have.WriteTimeout, have.IdleTimeout, timeouts)
+	}
+
+	client, err := rpc.Dial(srv.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Call(nil, "test_sleep", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// requests running past the write timeout are answered with a timeout error before the connection is cut
+	start := time.Now()
+	err = client.Call(nil, "test_sleep", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("have %v, want a timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timed out after %v, want the write timeout", elapsed)
+	}
+
+	// timeouts below a second are replaced with the defaults
+	srv = startTestEndpoint(t, nil, []string{"*"}, rpc.HTTPTimeouts{})
+	if srv.server.WriteTimeout != rpc.DefaultHTTPTimeouts.WriteTimeout {
+		t.Fatalf("have write timeout %v, want the default %v", srv.server.WriteTimeout, rpc.DefaultHTTPTimeouts.WriteTimeout)
+	}
+}
@@ -17,10 +17,11 @@
 package rpc
 
 import (
-	"fmt"
+	"errors"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -36,8 +37,7 @@ var (
 // ipcListen will create a Unix socket on the given endpoint.
 func ipcListen(endpoint string) (net.Listener, error) {
 	if len(endpoint) > int(maxPathSize) {
-		log.Warn(fmt.Sprintf("The ipc endpoint is longer than %d characters. ", maxPathSize),
-			"endpoint", endpoint)
+		log.Warnf("The ipc endpoint is longer than %d characters: %s", maxPathSize, endpoint)
 	}
 
 	// Ensure the IPC path exists and remove any previous leftover
@@ -53,30 +53,105 @@ func ipcListen(endpoint string) (net.Listener, error) {
 	return l, nil
 }
 
-func ipcServe(srv *rpc.Server, listener net.Listener) {
+// IPCServer serves RPC requests over a Unix socket, tracking open connections so they can be closed on shutdown
+type IPCServer struct {
+	endpoint string
+	listener net.Listener
+	srv      *rpc.Server
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Endpoint returns the path of the Unix socket the server listens on
+func (s *IPCServer) Endpoint() string {
+	return s.endpoint
+}
+
+// serve accepts connections until the listener is closed, serving each one concurrently
+func (s *IPCServer) serve() {
+	defer s.wg.Done()
 	for {
-		conn, err := listener.Accept()
+		conn, err := s.listener.Accept()
 		if netutil.IsTemporaryError(err) {
 			log.WithError(err).Warn("rpc accept error")
 			continue
 		}
 		if err != nil {
-			log.WithError(err).Warn("unknown error")
-			continue
+			if !errors.Is(err, net.ErrClosed) {
+				log.WithError(err).Error("ipc listener failed")
+			}
+			return
+		}
+		conn = newMeteredConn(conn)
+		if !s.track(conn) {
+			conn.Close()
+			return
 		}
 		log.WithField("addr", conn.RemoteAddr()).Trace("accepted ipc connection")
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.srv.ServeCodec(rpc.NewCodec(conn), 0)
+		}()
+	}
+}
+
+func (s *IPCServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
 	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *IPCServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+// Close stops accepting connections, closes all open connections and removes the socket file
+func (s *IPCServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.listener.Close()
+	s.mu.Unlock()
+
+	// stopping the RPC server closes the codecs of all open connections
+	s.srv.Stop()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	if rmErr := os.Remove(s.endpoint); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	log.Infof("IPC endpoint closed %s", s.endpoint)
+	return err
 }
 
 // StartIPCEndpoint starts an IPC endpoint.
-func StartIPCEndpoint(ipcEndpoint string, apis []rpc.API) (net.Listener, *rpc.Server, error) {
+func StartIPCEndpoint(ipcEndpoint string, apis []rpc.API) (*IPCServer, *rpc.Server, error) {
 	// Register all the APIs exposed by the services.
 	handler := rpc.NewServer()
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return nil, nil, err
 		}
-		log.Debug("IPC registered", "namespace", api.Namespace)
+		log.Debugf("IPC registered namespace %s", api.Namespace)
 	}
 	// All APIs registered, start the IPC listener.
 	listener, err := ipcListen(ipcEndpoint)
@@ -84,6 +159,14 @@ func StartIPCEndpoint(ipcEndpoint string, apis []rpc.API) (net.Listener, *rpc.Se
 		return nil, nil, err
 	}
 
-	go ipcServe(handler, listener)
-	return listener, handler, nil
+	server := &IPCServer{
+		endpoint: ipcEndpoint,
+		listener: listener,
+		srv:      handler,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	server.wg.Add(1)
+	go server.serve()
+	log.Infof("IPC endpoint opened %s", ipcEndpoint)
+	return server, handler, nil
 }
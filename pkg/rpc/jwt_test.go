@@ -0,0 +1,91 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// jwtCase is an authorization header sent to an endpoint requiring testJWTSecret, and whether it should be accepted
+type jwtCase struct {
+	name   string
+	header http.Header
+	ok     bool
+}
+
+func testJWTHeaders(t *testing.T) []jwtCase {
+	t.Helper()
+	token := func(secret []byte, iat time.Time) http.Header {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": iat.Unix()}).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return http.Header{"Authorization": []string{"Bearer " + signed}}
+	}
+	return []jwtCase{
+		{"no token", http.Header{}, false},
+		{"bad token", token([]byte("fedcba9876543210fedcba9876543210"), time.Now()), false},
+		{"expired iat", token(testJWTSecret, time.Now().Add(-5*time.Minute)), false},
+		{"valid token", token(testJWTSecret, time.Now()), true},
+	}
+}
+
+func TestHTTPJWT(t *testing.T) {
+	srv, _, err := StartHTTPEndpoint("127.0.0.1:0", testAPIs, []string{"test"}, nil, []string{"*"}, testJWTSecret, nil, rpc.DefaultHTTPTimeouts, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	for _, tt := range testJWTHeaders(t) {
+		resp := post(t, srv.Endpoint(), "", "test_echo", tt.header)
+		if want := map[bool]int{true: http.StatusOK, false: http.StatusUnauthorized}[tt.ok]; resp.StatusCode != want {
+			t.Fatalf("%s: have status %d, want %d", tt.name, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestWSJWT(t *testing.T) {
+	srv, _, err := StartWSEndpoint("127.0.0.1:0", testAPIs, []string{"test"}, nil, testJWTSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	for _, tt := range testJWTHeaders(t) {
+		client, err := rpc.DialOptions(context.Background(), srv.Endpoint(), rpc.WithHeaders(tt.header))
+		if tt.ok != (err == nil) {
+			t.Fatalf("%s: have handshake error %v, want accepted %v", tt.name, err, tt.ok)
+		}
+		if err != nil {
+			continue
+		}
+		var res string
+		if err := client.Call(&res, "test_echo", "hello"); err != nil || res != "hello" {
+			t.Fatalf("%s: have %q, %v", tt.name, res, err)
+		}
+		client.Close()
+	}
+}
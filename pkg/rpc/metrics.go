@@ -0,0 +1,93 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricsPath is the path the Prometheus metrics are served on
+const MetricsPath = "/metrics"
+
+// StartMetricsEndpoint serves the metrics registry in the Prometheus exposition format
+// Metrics are only recorded if metrics.Enabled is set before the database and the RPC endpoints are opened
+func StartMetricsEndpoint(endpoint string) (*HTTPServer, error) {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, prometheus.Handler(metrics.DefaultRegistry))
+	httpSrv, addr, err := startHTTPServer(endpoint, rpc.DefaultHTTPTimeouts, mux, nil)
+	if err != nil {
+		return nil, err
+	}
+	metricsURL := fmt.Sprintf("http://%v%s", addr, MetricsPath)
+	log.Infof("metrics endpoint opened %s", metricsURL)
+	return &HTTPServer{name: "metrics", endpoint: metricsURL, server: httpSrv}, nil
+}
+
+// meteredListener counts the open connections of a listener and the bytes read and written through them
+type meteredListener struct {
+	net.Listener
+}
+
+func (l meteredListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newMeteredConn(conn), nil
+}
+
+// meteredConn records the traffic of a single connection
+type meteredConn struct {
+	net.Conn
+	once sync.Once
+}
+
+func newMeteredConn(conn net.Conn) net.Conn {
+	if !metrics.Enabled {
+		return conn
+	}
+	metrics.GetOrRegisterGauge("leveldb/connections/active", nil).Inc(1)
+	metrics.GetOrRegisterCounter("leveldb/connections/total", nil).Inc(1)
+	return &meteredConn{Conn: conn}
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	metrics.GetOrRegisterMeter("leveldb/traffic/ingress", nil).Mark(int64(n))
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	metrics.GetOrRegisterMeter("leveldb/traffic/egress", nil).Mark(int64(n))
+	return n, err
+}
+
+func (c *meteredConn) Close() error {
+	c.once.Do(func() {
+		metrics.GetOrRegisterGauge("leveldb/connections/active", nil).Dec(1)
+	})
+	return c.Conn.Close()
+}
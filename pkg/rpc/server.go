@@ -0,0 +1,84 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// HTTPServer is a running HTTP listener, serving an HTTP, websocket or metrics endpoint
+type HTTPServer struct {
+	name     string
+	endpoint string
+	server   *http.Server
+	srvs     []*rpc.Server
+}
+
+// Endpoint returns the address the server listens on
+func (s *HTTPServer) Endpoint() string {
+	return s.endpoint
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to complete until ctx is done,
+// after which the remaining connections are closed. It then stops the RPC servers, closing any websocket connections.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		log.Warnf("%s endpoint graceful shutdown timed out, closing open connections", s.name)
+		err = s.server.Close()
+	}
+	for _, srv := range s.srvs {
+		srv.Stop()
+	}
+	log.Infof("%s endpoint closed %s", s.name, s.endpoint)
+	return err
+}
+
+// startHTTPServer starts an HTTP server on the endpoint, serving HTTPS if tlsConfig is not nil
+func startHTTPServer(endpoint string, timeouts rpc.HTTPTimeouts, handler http.Handler, tlsConfig *tls.Config) (*http.Server, net.Addr, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	if metrics.Enabled {
+		listener = meteredListener{listener}
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	// make sure timeout values are meaningful
+	node.CheckTimeouts(&timeouts)
+	httpSrv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       timeouts.ReadTimeout,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+		TLSConfig:         tlsConfig,
+	}
+	go httpSrv.Serve(listener)
+	return httpSrv, listener.Addr(), nil
+}
@@ -0,0 +1,65 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// StartWSEndpoint starts a websocket endpoint, configured with origins/modules.
+// If jwtSecret is not empty, the websocket handshake must carry a JWT signed with it.
+// The default database is served on / if apis is not empty, and every named database on /<Name>.
+func StartWSEndpoint(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, jwtSecret []byte, databases []Database) (*HTTPServer, *rpc.Server, error) {
+
+	mux := http.NewServeMux()
+	var srv *rpc.Server
+	var srvs []*rpc.Server
+	if len(apis) > 0 {
+		var err error
+		if srv, err = newRPCServer("WS", apis, modules); err != nil {
+			return nil, nil, err
+		}
+		srvs = append(srvs, srv)
+		mux.Handle("/", node.NewWSHandlerStack(srv.WebsocketHandler(wsOrigins), jwtSecret))
+	}
+	for _, db := range databases {
+		dbSrv, err := newRPCServer("WS", db.APIs, modules)
+		if err != nil {
+			stopAll(srvs)
+			return nil, nil, err
+		}
+		srvs = append(srvs, dbSrv)
+		mux.Handle("/"+db.Name, node.NewWSHandlerStack(dbSrv.WebsocketHandler(wsOrigins), jwtSecret))
+		log.Infof("WS serving database %s on /%s", db.Name, db.Name)
+	}
+
+	// start websocket server
+	httpSrv, addr, err := startHTTPServer(endpoint, rpc.DefaultHTTPTimeouts, mux, nil)
+	if err != nil {
+		stopAll(srvs)
+		return nil, nil, fmt.Errorf("could not start WS api: %w", err)
+	}
+	wsURL := fmt.Sprintf("ws://%v/", addr)
+	log.Infof("WS endpoint opened %s", wsURL)
+
+	return &HTTPServer{name: "WS", endpoint: wsURL, server: httpSrv, srvs: srvs}, srv, nil
+}
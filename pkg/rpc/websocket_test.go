@@ -0,0 +1,80 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// startTestWSEndpoint serves testAPIs over websockets on a free local port, shut down when the test ends
+func startTestWSEndpoint(t *testing.T, origins []string) *HTTPServer {
+	t.Helper()
+	srv, _, err := StartWSEndpoint("127.0.0.1:0", testAPIs, []string{"test"}, origins, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return srv
+}
+
+// dialWS reports whether a websocket sent with origin is accepted, and if so whether method can be called
+func dialWS(t *testing.T, url, origin, method string) (bool, error) {
+	t.Helper()
+	client, err := rpc.DialWebsocket(context.Background(), url, origin)
+	if err != nil {
+		return false, nil
+	}
+	defer client.Close()
+	var res string
+	return true, client.Call(&res, method, "hello")
+}
+
+func TestWSOrigins(t *testing.T) {
+	srv := startTestWSEndpoint(t, nil)
+	for _, tt := range []struct {
+		origin string
+		ok     bool
+	}{
+		{"http://localhost", true},
+		// clients other than browsers don't send an origin
+		{"", true},
+		{"http://attacker.example", false},
+	} {
+		ok, err := dialWS(t, srv.Endpoint(), tt.origin, "test_echo")
+		if ok != tt.ok || err != nil {
+			t.Fatalf("origin %q: have accepted %v, %v, want %v", tt.origin, ok, err, tt.ok)
+		}
+	}
+
+	srv = startTestWSEndpoint(t, []string{"http://explorer.example"})
+	if ok, err := dialWS(t, srv.Endpoint(), "http://explorer.example", "test_echo"); !ok || err != nil {
+		t.Fatalf("configured origin: have accepted %v, %v", ok, err)
+	}
+	if ok, _ := dialWS(t, srv.Endpoint(), "http://attacker.example", "test_echo"); ok {
+		t.Fatal("origin not configured accepted")
+	}
+}
+
+func TestWSModules(t *testing.T) {
+	srv := startTestWSEndpoint(t, nil)
+	if ok, err := dialWS(t, srv.Endpoint(), "", "hidden_echo"); !ok || err == nil {
+		t.Fatalf("have accepted %v, %v, want the namespace not to be served", ok, err)
+	}
+}
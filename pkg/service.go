@@ -17,18 +17,27 @@
 package leveldb_ethdb_rpc
 
 import (
+	"net/http"
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/metrics"
 	ethnode "github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 	log "github.com/sirupsen/logrus"
 )
 
+// reapInterval is how often idle iterators and expired snapshots are released, and the head block is checked
+const reapInterval = 10 * time.Second
+
 // Server is the top level interface for exposing a remote RPC wrapper around levelDB ethdb.Database
 type Server interface {
 	ethnode.Lifecycle
 	APIs() []rpc.API
+	BinaryHandler() http.Handler
+	HealthHandler() http.Handler
+	ReadyHandler() http.Handler
 	Protocols() []p2p.Protocol
 	Serve(wg *sync.WaitGroup)
 }
@@ -37,16 +46,29 @@ type Server interface {
 type Service struct {
 	wg       *sync.WaitGroup
 	backend  *LevelDBBackend
+	api      *PublicLevelDBAPI
+	limiter  *RateLimiter
+	health   *healthMonitor
 	quitChan chan struct{}
+	// loopDone is closed once the listening loop has released all server-side state
+	loopDone chan struct{}
+	stopOnce sync.Once
 }
 
 // NewServer creates a new Server using an underlying Service struct
-func NewServer(conf *Config) (Server, error) {
+// The calls to its APIs are admitted by limiter, which can be shared between servers, unless it is nil
+func NewServer(conf *Config, limiter *RateLimiter) (Server, error) {
 	sap := new(Service)
 	sap.quitChan = make(chan struct{})
+	sap.limiter = limiter
 	var err error
 	sap.backend, err = NewLevelDBBackend(conf)
-	return sap, err
+	if err != nil {
+		return nil, err
+	}
+	sap.api = NewPublicLevelDBAPI(sap.backend, sap.limiter)
+	sap.health = newHealthMonitor(sap.backend, conf.HealthStaleness)
+	return sap, nil
 }
 
 // Protocols exports the services p2p protocols, this service has none
@@ -60,22 +82,66 @@ func (sap *Service) APIs() []rpc.API {
 		{
 			Namespace: APIName,
 			Version:   APIVersion,
-			Service:   NewPublicLevelDBAPI(sap.backend),
+			Service:   sap.api,
+			Public:    true,
+		},
+		{
+			Namespace: ChainDataAPIName,
+			Version:   ChainDataAPIVersion,
+			Service:   NewPublicChainDataAPI(sap.backend, sap.limiter),
 			Public:    true,
 		},
 	}
 }
 
+// BinaryHandler returns the HTTP handler of the binary transport, to be served on BinaryPath
+func (sap *Service) BinaryHandler() http.Handler {
+	return NewBinaryHandler(sap.api)
+}
+
+// HealthHandler returns the HTTP handler reporting whether the database is open, to be served on HealthPath
+func (sap *Service) HealthHandler() http.Handler {
+	return sap.health.handler(false)
+}
+
+// ReadyHandler returns the HTTP handler reporting whether the database is open, has a head block
+// and the head has advanced within the staleness window, to be served on ReadyPath
+func (sap *Service) ReadyHandler() http.Handler {
+	return sap.health.handler(true)
+}
+
 // Serve is the listening loop
 func (sap *Service) Serve(wg *sync.WaitGroup) {
 	sap.wg = wg
+	sap.loopDone = make(chan struct{})
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
+		defer close(sap.loopDone)
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		var metricsTick <-chan time.Time
+		if metrics.Enabled {
+			sap.backend.collectMetrics()
+			metricsTicker := time.NewTicker(metricsInterval)
+			defer metricsTicker.Stop()
+			metricsTick = metricsTicker.C
+		}
 		for {
 			select {
+			case <-ticker.C:
+				sap.api.iterators.expire()
+				sap.api.snapshots.expire()
+				// keep track of head changes even when nobody polls the health handlers
+				sap.health.status()
+			case <-metricsTick:
+				sap.backend.collectMetrics()
 			case <-sap.quitChan:
 				log.Info("quiting the levelDB RPC server process")
+				sap.api.iterators.releaseAll()
+				sap.api.snapshots.releaseAll()
+				sap.api.inspector.stop()
+				sap.api.watcher.stop()
 				return
 			}
 		}
@@ -93,9 +159,21 @@ func (sap *Service) Start() error {
 }
 
 // Stop is used to close down the service
-// This is mostly just to satisfy the node.Service interface
+// It releases the open iterators and snapshots and closes the database; the endpoints serving
+// the service should be shut down first, so that no requests are in flight
 func (sap *Service) Stop() error {
-	log.Infof("stopping levelDB RPC server")
-	close(sap.quitChan)
-	return nil
+	var err error
+	sap.stopOnce.Do(func() {
+		log.Infof("stopping levelDB RPC server")
+		close(sap.quitChan)
+		if sap.loopDone != nil {
+			<-sap.loopDone
+		}
+		if err = sap.backend.Close(); err != nil {
+			log.WithError(err).Error("failed to close the database")
+			return
+		}
+		log.Infof("database %s closed", sap.backend.conf.FilePath)
+	})
+	return err
 }
@@ -0,0 +1,131 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSnapshotTTL is how long a snapshot may go unused before it is released, if the client doesn't ask otherwise
+	DefaultSnapshotTTL = 5 * time.Minute
+	// MaxSnapshotTTL is the longest idle time a client can ask for
+	MaxSnapshotTTL = time.Hour
+)
+
+var (
+	errSnapshotNotFound = errors.New("snapshot not found")
+	errTooManySnapshots = errors.New("too many open snapshots")
+)
+
+// remoteSnapshot wraps a database snapshot taken on behalf of a remote client
+type remoteSnapshot struct {
+	snap ethdb.Snapshot
+	// gen is the write generation of the backend at the time the snapshot was taken
+	gen      uint64
+	ttl      time.Duration
+	lastUsed time.Time // guarded by the snapshotStore lock
+}
+
+// snapshotStore tracks the snapshots that are currently held for remote clients, keyed by their ID
+type snapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[rpc.ID]*remoteSnapshot
+	// limit caps the number of snapshots held at the same time, unlimited if 0
+	limit int
+}
+
+func newSnapshotStore(limit int) *snapshotStore {
+	return &snapshotStore{snapshots: make(map[rpc.ID]*remoteSnapshot), limit: limit}
+}
+
+// add registers a new snapshot that is released once it goes unused for longer than ttl
+// the snapshot is released right away if the store already holds as many snapshots as its limit allows
+func (s *snapshotStore) add(snap ethdb.Snapshot, gen uint64, ttl time.Duration) (rpc.ID, error) {
+	if ttl <= 0 {
+		ttl = DefaultSnapshotTTL
+	}
+	if ttl > MaxSnapshotTTL {
+		ttl = MaxSnapshotTTL
+	}
+	id := rpc.NewID()
+	s.mu.Lock()
+	if s.limit > 0 && len(s.snapshots) >= s.limit {
+		s.mu.Unlock()
+		snap.Release()
+		return "", errTooManySnapshots
+	}
+	s.snapshots[id] = &remoteSnapshot{snap: snap, gen: gen, ttl: ttl, lastUsed: time.Now()}
+	s.mu.Unlock()
+	return id, nil
+}
+
+// get returns the snapshot with the given ID and refreshes its TTL
+func (s *snapshotStore) get(id rpc.ID) (*remoteSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.snapshots[id]
+	if !ok {
+		return nil, errSnapshotNotFound
+	}
+	rs.lastUsed = time.Now()
+	return rs, nil
+}
+
+// release releases the snapshot with the given ID, if it is still held
+func (s *snapshotStore) release(id rpc.ID) {
+	s.mu.Lock()
+	rs, ok := s.snapshots[id]
+	delete(s.snapshots, id)
+	s.mu.Unlock()
+	if ok {
+		rs.snap.Release()
+	}
+}
+
+// expire releases all snapshots whose TTL has elapsed since they were last used
+func (s *snapshotStore) expire() {
+	s.mu.Lock()
+	var stale []rpc.ID
+	for id, rs := range s.snapshots {
+		if time.Since(rs.lastUsed) > rs.ttl {
+			stale = append(stale, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range stale {
+		log.Debugf("releasing expired snapshot %s", id)
+		s.release(id)
+	}
+}
+
+// releaseAll releases every held snapshot
+func (s *snapshotStore) releaseAll() {
+	s.mu.Lock()
+	snapshots := s.snapshots
+	s.snapshots = make(map[rpc.ID]*remoteSnapshot)
+	s.mu.Unlock()
+	for _, rs := range snapshots {
+		rs.snap.Release()
+	}
+}
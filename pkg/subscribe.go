@@ -0,0 +1,281 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriptionPollInterval is how often the backend is checked for changes on behalf of subscribers
+const subscriptionPollInterval = time.Second
+
+// AncientsUpdate is pushed to subscribers whenever the freezer item count or tail changes
+type AncientsUpdate struct {
+	Ancients uint64 `json:"ancients"`
+	Tail     uint64 `json:"tail"`
+}
+
+// KeyUpdate is pushed to subscribers whenever the value stored under a watched key changes
+// Value is nil when the key is not present
+type KeyUpdate struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// AncientsChanged creates a subscription (leveldb_subscribe "ancientsChanged") that is notified
+// with the current freezer item count and tail, and again every time either of them changes
+func (s *PublicLevelDBAPI) AncientsChanged(ctx context.Context) (*rpc.Subscription, error) {
+	done, err := s.begin(ctx, "subscribe")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	s.watcher.add(&watch{notifier: notifier, sub: sub, ancients: true})
+	return sub, nil
+}
+
+// KeysChanged creates a subscription (leveldb_subscribe "keysChanged") that is notified with the
+// current value of each of the given keys, and again every time one of those values changes
+// At most MaxManyKeys keys can be watched by a single subscription
+func (s *PublicLevelDBAPI) KeysChanged(ctx context.Context, keys [][]byte) (*rpc.Subscription, error) {
+	done, err := s.begin(ctx, "subscribe")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if len(keys) > MaxManyKeys {
+		return nil, fmt.Errorf("too many keys requested: %d > %d", len(keys), MaxManyKeys)
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	s.watcher.add(&watch{notifier: notifier, sub: sub, keys: keys, lastKeys: make([]*KeyUpdate, len(keys))})
+	return sub, nil
+}
+
+// watch is a single ancientsChanged or keysChanged subscription, with the last values it was notified of
+type watch struct {
+	notifier *rpc.Notifier
+	sub      *rpc.Subscription
+
+	ancients     bool
+	lastAncients *AncientsUpdate
+
+	keys     [][]byte
+	lastKeys []*KeyUpdate
+}
+
+// watcher polls the backend on behalf of all ancientsChanged and keysChanged subscriptions,
+// reading the freezer counters and each watched key once per interval however many subscriptions there are
+// its loop only runs while there are subscriptions
+type watcher struct {
+	b      *LevelDBBackend
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// wake makes the loop poll right away, so that new subscriptions receive the current values without waiting
+	wake chan struct{}
+
+	mu      sync.Mutex
+	running bool
+	watches map[rpc.ID]*watch
+}
+
+func newWatcher(b *LevelDBBackend) *watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watcher{
+		b:       b,
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(chan struct{}, 1),
+		watches: make(map[rpc.ID]*watch),
+	}
+}
+
+// add registers the subscription, starting the loop if it isn't running
+func (w *watcher) add(wt *watch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return
+	}
+	w.watches[wt.sub.ID] = wt
+	if !w.running {
+		w.running = true
+		w.wg.Add(1)
+		go w.loop()
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *watcher) loop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(subscriptionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		case <-w.ctx.Done():
+			return
+		}
+		watches := w.active()
+		if watches == nil {
+			return
+		}
+		w.poll(watches)
+	}
+}
+
+// active drops the subscriptions that have ended and returns the others
+// it returns nil and marks the loop as stopped if none are left
+func (w *watcher) active() []*watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches := make([]*watch, 0, len(w.watches))
+	for id, wt := range w.watches {
+		select {
+		case <-wt.sub.Err():
+			delete(w.watches, id)
+		default:
+			watches = append(watches, wt)
+		}
+	}
+	if len(watches) == 0 {
+		w.running = false
+		return nil
+	}
+	return watches
+}
+
+func (w *watcher) remove(id rpc.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.watches, id)
+}
+
+// poll reads what the subscriptions watch and notifies those whose values changed
+func (w *watcher) poll(watches []*watch) {
+	var (
+		ancients    *AncientsUpdate
+		ancientsErr error
+		readAncient bool
+		values      = make(map[string]*KeyUpdate)
+	)
+	for _, wt := range watches {
+		if wt.ancients && !readAncient {
+			readAncient = true
+			if ancients, ancientsErr = readAncientsUpdate(w.b); ancientsErr != nil {
+				log.WithError(ancientsErr).Warn("failed to read ancients for subscription")
+			}
+		}
+		for _, key := range wt.keys {
+			if _, ok := values[string(key)]; ok {
+				continue
+			}
+			update, err := readKeyUpdate(w.b, key)
+			if err != nil {
+				log.WithError(err).Warn("failed to read watched key for subscription")
+			}
+			values[string(key)] = update
+		}
+	}
+	for _, wt := range watches {
+		if err := wt.notify(ancients, values); err != nil {
+			log.WithError(err).Debug("failed to notify subscriber")
+			w.remove(wt.sub.ID)
+		}
+	}
+}
+
+// notify pushes the values that changed since the last notification to the subscriber
+// values that couldn't be read (nil) are skipped
+func (wt *watch) notify(ancients *AncientsUpdate, values map[string]*KeyUpdate) error {
+	if wt.ancients && ancients != nil && (wt.lastAncients == nil || *wt.lastAncients != *ancients) {
+		if err := wt.notifier.Notify(wt.sub.ID, ancients); err != nil {
+			return err
+		}
+		wt.lastAncients = ancients
+	}
+	for i, key := range wt.keys {
+		update := values[string(key)]
+		if update == nil {
+			continue
+		}
+		last := wt.lastKeys[i]
+		if last != nil && bytes.Equal(last.Value, update.Value) && (last.Value == nil) == (update.Value == nil) {
+			continue
+		}
+		if err := wt.notifier.Notify(wt.sub.ID, update); err != nil {
+			return err
+		}
+		wt.lastKeys[i] = update
+	}
+	return nil
+}
+
+func readAncientsUpdate(b *LevelDBBackend) (*AncientsUpdate, error) {
+	ancients, err := b.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	tail, err := b.Tail()
+	if err != nil {
+		return nil, err
+	}
+	return &AncientsUpdate{Ancients: ancients, Tail: tail}, nil
+}
+
+func readKeyUpdate(b *LevelDBBackend, key []byte) (*KeyUpdate, error) {
+	ok, err := b.Has(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &KeyUpdate{Key: key}, nil
+	}
+	value, err := b.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return &KeyUpdate{Key: key, Value: value}, nil
+}
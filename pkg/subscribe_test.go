@@ -0,0 +1,177 @@
+// VulcanizeDB
+// Copyright © 2022 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb_ethdb_rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestSubscriptionClient serves a write-enabled api in process and returns it with a client connected to it
+func newTestSubscriptionClient(t *testing.T, entries map[string]string) (*PublicLevelDBAPI, *rpc.Client) {
+	t.Helper()
+	conf := newTestConfig(t, entries)
+	conf.WriteEnabled = true
+	api := NewPublicLevelDBAPI(newTestBackend(t, conf), nil)
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(APIName, api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(srv)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Stop()
+		api.watcher.stop()
+	})
+	return api, client
+}
+
+func receive[T any](t *testing.T, ch <-chan T, sub *rpc.ClientSubscription) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+	panic("unreachable")
+}
+
+func TestKeysChanged(t *testing.T) {
+	api, client := newTestSubscriptionClient(t, map[string]string{"a": "1"})
+	ctx := context.Background()
+
+	ch := make(chan *KeyUpdate, 8)
+	sub, err := client.Subscribe(ctx, APIName, ch, "keysChanged", [][]byte{[]byte("a"), []byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	initial := map[string][]byte{}
+	for i := 0; i < 2; i++ {
+		update := receive(t, ch, sub)
+		initial[string(update.Key)] = update.Value
+	}
+	if string(initial["a"]) != "1" || initial["b"] != nil {
+		t.Errorf("have initial values a=%q b=%q, want a=1 and b missing", initial["a"], initial["b"])
+	}
+
+	if err := api.Put(ctx, []byte("b"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	update := receive(t, ch, sub)
+	if string(update.Key) != "b" || string(update.Value) != "2" {
+		t.Errorf("have update %q=%q, want b=2", update.Key, update.Value)
+	}
+	select {
+	case update := <-ch:
+		t.Errorf("unexpected update %q=%q of an unchanged key", update.Key, update.Value)
+	case <-time.After(2 * subscriptionPollInterval):
+	}
+}
+
+func TestKeysChangedTooManyKeys(t *testing.T) {
+	_, client := newTestSubscriptionClient(t, nil)
+	keys := make([][]byte, MaxManyKeys+1)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("k%d", i))
+	}
+	_, err := client.Subscribe(context.Background(), APIName, make(chan *KeyUpdate), "keysChanged", keys)
+	if err == nil || !strings.Contains(err.Error(), "too many keys requested") {
+		t.Fatalf("have error %v, want too many keys requested", err)
+	}
+}
+
+func TestAncientsChanged(t *testing.T) {
+	api, client := newTestSubscriptionClient(t, nil)
+
+	ch := make(chan *AncientsUpdate, 8)
+	sub, err := client.Subscribe(context.Background(), APIName, ch, "ancientsChanged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	if update := receive(t, ch, sub); *update != (AncientsUpdate{}) {
+		t.Errorf("have initial update %+v, want an empty freezer", *update)
+	}
+
+	// append to the freezer as the node writing the database would
+	_, err = currentView(api.b).ethDB.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 2; i++ {
+			for _, kind := range []string{"headers", "hashes", "bodies", "receipts", "diffs"} {
+				if err := op.AppendRaw(kind, i, []byte{byte(i)}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update := receive(t, ch, sub); *update != (AncientsUpdate{Ancients: 2}) {
+		t.Errorf("have update %+v, want 2 ancients", *update)
+	}
+}
+
+func TestWatcherSharedLoop(t *testing.T) {
+	api, client := newTestSubscriptionClient(t, map[string]string{"a": "1"})
+	ctx := context.Background()
+
+	var subs []*rpc.ClientSubscription
+	for i := 0; i < 3; i++ {
+		ch := make(chan *KeyUpdate, 1)
+		sub, err := client.Subscribe(ctx, APIName, ch, "keysChanged", [][]byte{[]byte("a")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		receive(t, ch, sub)
+		subs = append(subs, sub)
+	}
+	api.watcher.mu.Lock()
+	watches, running := len(api.watcher.watches), api.watcher.running
+	api.watcher.mu.Unlock()
+	if watches != 3 || !running {
+		t.Fatalf("have %d watches (running %v), want 3 served by a running loop", watches, running)
+	}
+
+	// the loop exits once every subscription has ended
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		api.watcher.mu.Lock()
+		running = api.watcher.running
+		api.watcher.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the watcher loop is still running after every subscription ended")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}